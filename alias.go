@@ -0,0 +1,80 @@
+package goncanode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/nbah1990/goncanode/entities"
+	"strings"
+)
+
+var ErrNoSigningKey = errors.New("no signing key in the container")
+
+type keyAliasKey struct{}
+
+// withKeyAlias selects the key used by calls made with ctx, overriding the handler's KeyAlias and the key provider's alias.
+// SignKeyAlias is the public way to do it per call.
+func withKeyAlias(ctx context.Context, alias string) context.Context {
+	return context.WithValue(ctx, keyAliasKey{}, alias)
+}
+
+func keyAlias(ctx context.Context) (string, bool) {
+	a, ok := ctx.Value(keyAliasKey{}).(string)
+	return a, ok && a != ``
+}
+
+// KeyAliases lists the aliases of the handler's container with their key usages,
+// usages NCANode doesn't report along with the aliases (v1) are read from each key's certificate.
+func KeyAliases(ctx context.Context, h Handler) ([]entities.KeyAlias, error) {
+	aliases, err := h.Pkcs12Aliases(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, a := range aliases {
+		if a.KeyUsage != `` {
+			continue
+		}
+
+		c, err := h.Pkcs12Info(withKeyAlias(ctx, a.Alias))
+		if err != nil {
+			return nil, err
+		}
+		aliases[i].KeyUsage = c.KeyUsage
+	}
+
+	return aliases, nil
+}
+
+// SigningAlias picks the only signing (SIGN, not AUTH) key of the handler's container.
+func SigningAlias(ctx context.Context, h Handler) (string, error) {
+	aliases, err := KeyAliases(ctx, h)
+	if err != nil {
+		return ``, err
+	}
+
+	var signing []string
+	for _, a := range aliases {
+		if strings.EqualFold(a.KeyUsage, `SIGN`) {
+			signing = append(signing, a.Alias)
+		}
+	}
+
+	switch len(signing) {
+	case 0:
+		return ``, fmt.Errorf(`%w: aliases %q`, ErrNoSigningKey, aliasNames(aliases))
+	case 1:
+		return signing[0], nil
+	}
+
+	return ``, fmt.Errorf(`several signing keys in the container: %q, set the alias explicitly`, signing)
+}
+
+func aliasNames(aliases []entities.KeyAlias) []string {
+	s := make([]string, 0, len(aliases))
+	for _, a := range aliases {
+		s = append(s, a.Alias)
+	}
+
+	return s
+}
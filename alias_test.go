@@ -0,0 +1,97 @@
+package goncanode
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type rpcApiClient func(method string, params map[string]interface{}) []byte
+
+func (f rpcApiClient) Request(_ context.Context, _ string, _ string, data *bytes.Buffer) ([]byte, error) {
+	var r struct {
+		Method string                 `json:"method"`
+		Params map[string]interface{} `json:"params"`
+	}
+	if err := json.Unmarshal(data.Bytes(), &r); err != nil {
+		return nil, err
+	}
+
+	return f(r.Method, r.Params), nil
+}
+
+func TestKeyAlias(t *testing.T) {
+	t.Run("V3", func(t *testing.T) {
+		m := &mockApiClient{response: []byte(`{"status":200,"message":"Success","xml":"<signedXml></signedXml>"}`)}
+		handler := &NCANodeV3Handler{P12base64: "base64string", KeyAlias: "sign", Timeout: time.Second, Api: m}
+
+		_, _ = handler.SignWithSecurityHeader(context.Background(), "<xml></xml>", ``)
+		if !strings.Contains(string(m.body), `"keyAlias":"sign"`) {
+			t.Errorf("Expected handler alias in request, got: %s", m.body)
+		}
+
+		_, _ = handler.SignWithSecurityHeader(context.Background(), "<xml></xml>", ``, SignKeyAlias("other"))
+		if !strings.Contains(string(m.body), `"keyAlias":"other"`) {
+			t.Errorf("Expected per call alias in request, got: %s", m.body)
+		}
+	})
+
+	t.Run("V1", func(t *testing.T) {
+		m := &mockApiClientV1{response: []byte(`{"status":0,"message":"","result":{"xml":"<signedXml></signedXml>"}}`)}
+		handler := &NCANodeV1Handler{P12base64: "base64string", Timeout: time.Second, Api: m}
+
+		_, _ = handler.SignWithSecurityHeader(context.Background(), "<xml></xml>", ``)
+		if strings.Contains(string(m.body), `"alias"`) {
+			t.Errorf("Expected no alias without selection, got: %s", m.body)
+		}
+
+		_, _ = handler.SignWithSecurityHeader(context.Background(), "<xml></xml>", ``, SignKeyAlias("sign"))
+		if !strings.Contains(string(m.body), `"alias":"sign"`) {
+			t.Errorf("Expected alias in request, got: %s", m.body)
+		}
+	})
+}
+
+func TestSigningAlias(t *testing.T) {
+	usages := map[string]string{"auth_rsa": "AUTH", "sign_rsa": "SIGN"}
+	handler := &NCANodeV1Handler{
+		P12base64: "base64string",
+		Timeout:   time.Second,
+		Api: rpcApiClient(func(method string, params map[string]interface{}) []byte {
+			switch method {
+			case "PKCS12.aliases":
+				return []byte(`{"status":0,"result":{"aliases":["auth_rsa","sign_rsa"]}}`)
+			case "PKCS12.info":
+				return []byte(`{"status":0,"result":{"keyUsage":"` + usages[params["alias"].(string)] + `"}}`)
+			}
+			return []byte(`{"status":-1,"message":"unexpected method"}`)
+		}),
+	}
+
+	aliases, err := KeyAliases(context.Background(), handler)
+	if err != nil {
+		t.Fatalf("Expected no errors, got: %v", err)
+	}
+	if len(aliases) != 2 || aliases[0].KeyUsage != "AUTH" || aliases[1].KeyUsage != "SIGN" {
+		t.Errorf("Unexpected aliases: %+v", aliases)
+	}
+
+	alias, err := SigningAlias(context.Background(), handler)
+	if err != nil || alias != "sign_rsa" {
+		t.Errorf("Expected sign_rsa, got %q, err: %v", alias, err)
+	}
+
+	usages["sign_rsa"] = "AUTH"
+	if _, err := SigningAlias(context.Background(), handler); !errors.Is(err, ErrNoSigningKey) {
+		t.Errorf("Expected ErrNoSigningKey, got: %v", err)
+	}
+
+	usages["auth_rsa"], usages["sign_rsa"] = "SIGN", "SIGN"
+	if _, err := SigningAlias(context.Background(), handler); err == nil {
+		t.Errorf("Expected error for several signing keys")
+	}
+}
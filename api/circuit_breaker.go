@@ -0,0 +1,267 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrCircuitOpen = errors.New("api: circuit breaker is open")
+
+type BreakerState int
+
+const (
+	StateClosed BreakerState = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case StateClosed:
+		return `closed`
+	case StateOpen:
+		return `open`
+	case StateHalfOpen:
+		return `half-open`
+	}
+
+	return `unknown`
+}
+
+// CircuitBreaker opens once FailureRateThreshold of at least MinRequests requests within Window failed,
+// rejects everything with ErrCircuitOpen for Cooldown, then lets HalfOpenRequests probes through to decide
+// whether to close again.
+type CircuitBreaker struct {
+	Client IClient
+
+	FailureRateThreshold float64
+	MinRequests          int
+	Window               time.Duration
+	Cooldown             time.Duration
+	HalfOpenRequests     int
+	// IsFailure decides which errors count against the breaker, IsBreakerFailure is used when nil.
+	IsFailure     func(err error) bool
+	OnStateChange func(from BreakerState, to BreakerState)
+
+	mu          sync.Mutex
+	pending     [][2]BreakerState
+	state       BreakerState
+	requests    int
+	failures    int
+	windowStart time.Time
+	openedAt    time.Time
+	probes      int
+	successes   int
+}
+
+const (
+	DefaultFailureRateThreshold = 0.5
+	DefaultMinRequests          = 10
+	DefaultBreakerWindow        = time.Minute
+	DefaultCooldown             = 30 * time.Second
+)
+
+// NewCircuitBreaker returns a breaker with the default settings, zero fields of a CircuitBreaker fall back to them too.
+func NewCircuitBreaker(c IClient) *CircuitBreaker {
+	return &CircuitBreaker{
+		Client:               c,
+		FailureRateThreshold: DefaultFailureRateThreshold,
+		MinRequests:          DefaultMinRequests,
+		Window:               DefaultBreakerWindow,
+		Cooldown:             DefaultCooldown,
+		HalfOpenRequests:     1,
+	}
+}
+
+// IsBreakerFailure counts transport failures, timeouts and 5xx responses, but neither 4xx nor caller cancellation.
+func IsBreakerFailure(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode >= 500
+	}
+
+	return true
+}
+
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.unlock()
+
+	b.refresh(time.Now())
+
+	return b.state
+}
+
+func (b *CircuitBreaker) Request(ctx context.Context, method string, url string, data *bytes.Buffer) ([]byte, error) {
+	return b.request(ctx, b.Client, method, url, data)
+}
+
+// Wrap guards c with the state of b and leaves b.Client alone, so one breaker can be shared by several clients.
+func (b *CircuitBreaker) Wrap(c IClient) IClient {
+	return &breakerClient{breaker: b, client: c}
+}
+
+type breakerClient struct {
+	breaker *CircuitBreaker
+	client  IClient
+}
+
+func (c *breakerClient) Request(ctx context.Context, method string, url string, data *bytes.Buffer) ([]byte, error) {
+	return c.breaker.request(ctx, c.client, method, url, data)
+}
+
+func (b *CircuitBreaker) request(ctx context.Context, c IClient, method string, url string, data *bytes.Buffer) (result []byte, err error) {
+	probe, err := b.allow()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err = c.Request(ctx, method, url, data)
+
+	isFailure := b.IsFailure
+	if isFailure == nil {
+		isFailure = IsBreakerFailure
+	}
+
+	b.record(probe, isFailure(err))
+
+	return
+}
+
+func (b *CircuitBreaker) allow() (probe bool, err error) {
+	b.mu.Lock()
+	defer b.unlock()
+
+	b.refresh(time.Now())
+
+	switch b.state {
+	case StateOpen:
+		return false, ErrCircuitOpen
+	case StateHalfOpen:
+		if b.probes >= b.halfOpenRequests() {
+			return false, ErrCircuitOpen
+		}
+		b.probes++
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func (b *CircuitBreaker) record(probe bool, failed bool) {
+	b.mu.Lock()
+	defer b.unlock()
+
+	now := time.Now()
+
+	if probe {
+		if b.state != StateHalfOpen {
+			return
+		}
+		if failed {
+			b.setState(StateOpen, now)
+			return
+		}
+		b.successes++
+		if b.successes >= b.halfOpenRequests() {
+			b.setState(StateClosed, now)
+		}
+		return
+	}
+
+	if b.state != StateClosed {
+		return
+	}
+
+	b.requests++
+	if failed {
+		b.failures++
+	}
+
+	minRequests := b.MinRequests
+	if minRequests <= 0 {
+		minRequests = DefaultMinRequests
+	}
+	threshold := b.FailureRateThreshold
+	if threshold <= 0 {
+		threshold = DefaultFailureRateThreshold
+	}
+
+	if b.requests >= minRequests && float64(b.failures)/float64(b.requests) >= threshold {
+		b.setState(StateOpen, now)
+	}
+}
+
+// refresh moves an open breaker to half-open after Cooldown and restarts the closed state counting window.
+func (b *CircuitBreaker) refresh(now time.Time) {
+	cooldown := b.Cooldown
+	if cooldown <= 0 {
+		cooldown = DefaultCooldown
+	}
+	window := b.Window
+	if window <= 0 {
+		window = DefaultBreakerWindow
+	}
+
+	switch b.state {
+	case StateOpen:
+		if now.Sub(b.openedAt) >= cooldown {
+			b.setState(StateHalfOpen, now)
+		}
+	case StateClosed:
+		if now.Sub(b.windowStart) >= window {
+			b.windowStart = now
+			b.requests = 0
+			b.failures = 0
+		}
+	}
+}
+
+func (b *CircuitBreaker) setState(s BreakerState, now time.Time) {
+	from := b.state
+
+	b.state = s
+	b.requests = 0
+	b.failures = 0
+	b.windowStart = now
+	b.probes = 0
+	b.successes = 0
+
+	if s == StateOpen {
+		b.openedAt = now
+	}
+
+	if from != s {
+		b.pending = append(b.pending, [2]BreakerState{from, s})
+	}
+}
+
+func (b *CircuitBreaker) halfOpenRequests() int {
+	if b.HalfOpenRequests <= 0 {
+		return 1
+	}
+
+	return b.HalfOpenRequests
+}
+
+// unlock releases the breaker and only then reports state changes, so OnStateChange may call State.
+func (b *CircuitBreaker) unlock() {
+	pending := b.pending
+	b.pending = nil
+	b.mu.Unlock()
+
+	if b.OnStateChange == nil {
+		return
+	}
+
+	for _, p := range pending {
+		b.OnStateChange(p[0], p[1])
+	}
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type switchClient struct {
+	err   error
+	calls int
+}
+
+func (m *switchClient) Request(_ context.Context, _ string, _ string, _ *bytes.Buffer) ([]byte, error) {
+	m.calls++
+	return nil, m.err
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	m := &switchClient{err: context.DeadlineExceeded}
+	var transitions []string
+
+	b := NewCircuitBreaker(m)
+	b.MinRequests = 4
+	b.FailureRateThreshold = 0.5
+	b.Cooldown = 30 * time.Millisecond
+	b.OnStateChange = func(from BreakerState, to BreakerState) {
+		transitions = append(transitions, from.String()+">"+to.String()+">"+b.State().String())
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 4; i++ {
+		_, _ = b.Request(ctx, "POST", "/", &bytes.Buffer{})
+	}
+
+	if b.State() != StateOpen {
+		t.Fatalf("Expected open state, got %s", b.State())
+	}
+
+	_, err := b.Request(ctx, "POST", "/", &bytes.Buffer{})
+	if !errors.Is(err, ErrCircuitOpen) || m.calls != 4 {
+		t.Errorf("Expected fail fast with ErrCircuitOpen, got %v after %d calls", err, m.calls)
+	}
+
+	time.Sleep(40 * time.Millisecond)
+	if b.State() != StateHalfOpen {
+		t.Fatalf("Expected half-open state after cooldown, got %s", b.State())
+	}
+
+	_, _ = b.Request(ctx, "POST", "/", &bytes.Buffer{})
+	if b.State() != StateOpen {
+		t.Fatalf("Expected failed probe to reopen, got %s", b.State())
+	}
+
+	time.Sleep(40 * time.Millisecond)
+	m.err = nil
+	if _, err = b.Request(ctx, "POST", "/", &bytes.Buffer{}); err != nil {
+		t.Fatalf("Expected successful probe, got %v", err)
+	}
+	if b.State() != StateClosed {
+		t.Fatalf("Expected successful probe to close, got %s", b.State())
+	}
+
+	want := []string{"closed>open>open", "open>half-open>half-open", "half-open>open>open", "open>half-open>half-open", "half-open>closed>closed"}
+	if len(transitions) != len(want) {
+		t.Fatalf("Expected transitions %v, got %v", want, transitions)
+	}
+	for i := range want {
+		if transitions[i] != want[i] {
+			t.Errorf("Expected transition %s, got %s", want[i], transitions[i])
+		}
+	}
+}
+
+func TestCircuitBreaker_FailureRate(t *testing.T) {
+	m := &switchClient{}
+	b := &CircuitBreaker{Client: m, MinRequests: 4, FailureRateThreshold: 0.75}
+
+	ctx := context.Background()
+	for i := 0; i < 8; i++ {
+		if i%2 == 0 {
+			m.err = &HTTPError{StatusCode: http.StatusBadGateway}
+		} else {
+			m.err = nil
+		}
+		_, _ = b.Request(ctx, "POST", "/", &bytes.Buffer{})
+	}
+
+	if b.State() != StateClosed {
+		t.Errorf("Expected closed state below failure rate, got %s", b.State())
+	}
+}
+
+func TestIsBreakerFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"Nil", nil, false},
+		{"Canceled", context.Canceled, false},
+		{"DeadlineExceeded", context.DeadlineExceeded, true},
+		{"BadRequest", &HTTPError{StatusCode: http.StatusBadRequest}, false},
+		{"ServiceUnavailable", &HTTPError{StatusCode: http.StatusServiceUnavailable}, true},
+		{"Network", errors.New("connection reset"), true},
+	}
+
+	for _, tt := range tests {
+		if got := IsBreakerFailure(tt.err); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
@@ -3,9 +3,16 @@ package api
 import (
 	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"github.com/nbah1990/goncanode/metrics"
+	"github.com/nbah1990/goncanode/tracing"
 	"io"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type IClient interface {
@@ -15,19 +22,71 @@ type IClient interface {
 type Client struct {
 	BaseUrl    string
 	HTTPClient *http.Client
+	// Decorators are applied in order to every request, after Content-Type is set.
+	Decorators []RequestDecorator
+	// Logger gets a debug record per http exchange without bodies, nothing is logged when nil.
+	Logger *slog.Logger
+	// Tracer gets a span per http exchange and injects its context into the request headers.
+	Tracer tracing.Tracer
+	// Metrics gets a count and latency of every http exchange labeled with BaseUrl as the node.
+	Metrics metrics.Collector
 }
 
 func (c *Client) Request(ctx context.Context, method string, url string, data *bytes.Buffer) (result []byte, err error) {
-	req, err := http.NewRequestWithContext(ctx, method, c.resolveTrimmedUrl(url), data)
+	var payload []byte
+	if data != nil {
+		payload = data.Bytes()
+	}
+
+	result, err = c.do(ctx, method, url, payload)
+
+	// stale credentials get one more chance with freshly fetched ones
+	if httpErr, ok := err.(*HTTPError); ok && httpErr.StatusCode == http.StatusUnauthorized && c.invalidate() {
+		result, err = c.do(ctx, method, url, payload)
+	}
+
+	return
+}
+
+func (c *Client) do(ctx context.Context, method string, url string, payload []byte) (result []byte, err error) {
+	fullUrl := c.resolveTrimmedUrl(url)
+	status := 0
+	start := time.Now()
+
+	var span tracing.Span
+	if c.Tracer != nil {
+		ctx, span = c.Tracer.Start(ctx, `HTTP `+method,
+			tracing.String(tracing.AttrHTTPMethod, method),
+			tracing.String(tracing.AttrHTTPUrl, fullUrl),
+		)
+	}
+
+	defer func() {
+		c.endSpan(span, status, start, err)
+		c.record(method, url, status, start)
+		c.log(ctx, method, fullUrl, status, start, len(payload), len(result), err)
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, method, fullUrl, bytes.NewReader(payload))
 	if err != nil {
 		return
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 
+	for _, d := range c.Decorators {
+		if err = d.Decorate(req); err != nil {
+			return nil, fmt.Errorf(`api: can't decorate request: %w`, err)
+		}
+	}
+
+	if c.Tracer != nil {
+		c.Tracer.Inject(ctx, req.Header)
+	}
+
 	client := c.HTTPClient
 	if client == nil {
-		client = &http.Client{}
+		client = defaultHTTPClient
 	}
 
 	resp, err := client.Do(req)
@@ -42,14 +101,108 @@ func (c *Client) Request(ctx context.Context, method string, url string, data *b
 		}
 	}(resp.Body)
 
+	status = resp.StatusCode
+
 	result, err = io.ReadAll(resp.Body)
 	if err != nil {
 		return
 	}
 
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return result, newHTTPError(resp, result)
+	}
+
 	return
 }
 
+func (c *Client) record(method string, url string, status int, start time.Time) {
+	if c.Metrics == nil {
+		return
+	}
+
+	path := `/` + strings.TrimLeft(url, `/`)
+
+	c.Metrics.ObserveHistogram(metrics.HTTPRequestDuration, time.Since(start).Seconds(), metrics.Labels{
+		`node`:   c.BaseUrl,
+		`path`:   path,
+		`method`: method,
+	})
+
+	c.Metrics.AddCounter(metrics.HTTPRequestsTotal, 1, metrics.Labels{
+		`node`:   c.BaseUrl,
+		`path`:   path,
+		`method`: method,
+		`code`:   strconv.Itoa(status),
+	})
+}
+
+func (c *Client) log(ctx context.Context, method string, url string, status int, start time.Time, requestBytes int, responseBytes int, err error) {
+	if c.Logger == nil || !c.Logger.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String(`method`, method),
+		slog.String(`url`, url),
+		slog.Int(`status`, status),
+		slog.Duration(`duration`, time.Since(start)),
+		slog.Int(`request_bytes`, requestBytes),
+		slog.Int(`response_bytes`, responseBytes),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String(`error`, err.Error()))
+	}
+
+	c.Logger.LogAttrs(ctx, slog.LevelDebug, `ncanode http request`, attrs...)
+}
+
+func (c *Client) endSpan(span tracing.Span, status int, start time.Time, err error) {
+	if span == nil {
+		return
+	}
+
+	span.SetAttributes(
+		tracing.Int(tracing.AttrHTTPStatusCode, status),
+		tracing.Float64(tracing.AttrLatencyMs, float64(time.Since(start))/float64(time.Millisecond)),
+	)
+
+	if err != nil {
+		span.SetAttributes(tracing.String(tracing.AttrErrorClass, errorClass(err)))
+		span.RecordError(err)
+	}
+
+	span.End()
+}
+
+// errorClass tells http status errors from network failures and the caller giving up.
+func errorClass(err error) string {
+	var httpErr *HTTPError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return `timeout`
+	case errors.Is(err, context.Canceled):
+		return `canceled`
+	case errors.As(err, &httpErr):
+		return fmt.Sprintf(`http_%dxx`, httpErr.StatusCode/100)
+	case IsRetryable(err):
+		return `network`
+	}
+
+	return `other`
+}
+
+func (c *Client) invalidate() bool {
+	invalidated := false
+	for _, d := range c.Decorators {
+		if i, ok := d.(invalidator); ok {
+			i.Invalidate()
+			invalidated = true
+		}
+	}
+
+	return invalidated
+}
+
 func (c *Client) resolveTrimmedUrl(url string) string {
 	p1 := strings.TrimRight(c.BaseUrl, "/")
 	p2 := strings.TrimLeft(url, "/")
@@ -4,7 +4,10 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"github.com/nbah1990/goncanode/metrics"
+	"github.com/nbah1990/goncanode/tracing"
 	"io"
+	"log/slog"
 	"net/http"
 	"strings"
 	"testing"
@@ -247,3 +250,160 @@ func TestClient_resolveTrimmedUrl(t *testing.T) {
 		}
 	}
 }
+
+func TestClient_Request_NonSuccessStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		body       string
+		truncated  bool
+	}{
+		{"JsonBody", http.StatusBadRequest, `{"status":400,"message":"Bad Request"}`, false},
+		{"HtmlBody", http.StatusBadGateway, `<html><body>502 Bad Gateway</body></html>`, false},
+		{"LongBody", http.StatusInternalServerError, strings.Repeat("x", MaxErrorBodyLength+1), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Client{
+				BaseUrl: "https://example.com",
+				HTTPClient: &http.Client{
+					Transport: &mockRoundTripper{
+						roundTripFunc: func(req *http.Request) (*http.Response, error) {
+							header := make(http.Header)
+							header.Set("X-Request-Id", "42")
+							return &http.Response{
+								StatusCode: tt.statusCode,
+								Body:       io.NopCloser(strings.NewReader(tt.body)),
+								Header:     header,
+							}, nil
+						},
+					},
+				},
+			}
+
+			result, err := c.Request(context.Background(), "POST", "/path", &bytes.Buffer{})
+			var httpErr *HTTPError
+			if !errors.As(err, &httpErr) {
+				t.Fatalf("Expected *HTTPError, got %v", err)
+			}
+			if httpErr.StatusCode != tt.statusCode {
+				t.Errorf("Expected status %d, got %d", tt.statusCode, httpErr.StatusCode)
+			}
+			if httpErr.Header.Get("X-Request-Id") != "42" {
+				t.Errorf("Expected response headers in error, got %v", httpErr.Header)
+			}
+			if httpErr.Truncated != tt.truncated || len(httpErr.Body) > MaxErrorBodyLength {
+				t.Errorf("Unexpected body truncation, truncated: %v, length: %d", httpErr.Truncated, len(httpErr.Body))
+			}
+			if string(result) != tt.body {
+				t.Errorf("Expected full body as result, got %s", string(result))
+			}
+		})
+	}
+}
+
+func TestClient_Request_Logger(t *testing.T) {
+	var buf bytes.Buffer
+	c := &Client{
+		BaseUrl: "https://example.com",
+		HTTPClient: &http.Client{Transport: &mockRoundTripper{roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: 503, Body: io.NopCloser(strings.NewReader(`{"message":"busy"}`)), Header: make(http.Header)}, nil
+		}}},
+		Logger: slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})),
+	}
+
+	_, _ = c.Request(context.Background(), http.MethodPost, "/cms/sign", bytes.NewBufferString(`{"key":"secret"}`))
+
+	out := buf.String()
+	for _, s := range []string{"method=POST", "url=https://example.com/cms/sign", "status=503", "request_bytes=16", "response_bytes=18", "error="} {
+		if !strings.Contains(out, s) {
+			t.Errorf("Expected %q in log record, got: %s", s, out)
+		}
+	}
+	if strings.Contains(out, "secret") {
+		t.Errorf("Expected no request body in log record, got: %s", out)
+	}
+}
+
+type headerTracer struct {
+	attrs map[string]interface{}
+	ended bool
+}
+
+func (t *headerTracer) Start(ctx context.Context, _ string, attrs ...tracing.Attribute) (context.Context, tracing.Span) {
+	t.SetAttributes(attrs...)
+	return ctx, t
+}
+
+func (t *headerTracer) Inject(_ context.Context, h http.Header) {
+	h.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+}
+
+func (t *headerTracer) SetAttributes(attrs ...tracing.Attribute) {
+	for _, a := range attrs {
+		t.attrs[a.Key] = a.Value
+	}
+}
+
+func (t *headerTracer) RecordError(error) {}
+
+func (t *headerTracer) End() {
+	t.ended = true
+}
+
+func TestClient_Request_Tracer(t *testing.T) {
+	tracer := &headerTracer{attrs: map[string]interface{}{}}
+	c := &Client{
+		BaseUrl: "https://example.com",
+		HTTPClient: &http.Client{Transport: &mockRoundTripper{roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get("traceparent") == "" {
+				t.Errorf("Expected trace context header to be injected")
+			}
+			return &http.Response{StatusCode: 502, Body: io.NopCloser(strings.NewReader(``)), Header: make(http.Header)}, nil
+		}}},
+		Tracer: tracer,
+	}
+
+	_, _ = c.Request(context.Background(), http.MethodPost, "/xml/verify", &bytes.Buffer{})
+
+	if !tracer.ended || tracer.attrs[tracing.AttrHTTPStatusCode] != 502 || tracer.attrs[tracing.AttrErrorClass] != "http_5xx" || tracer.attrs[tracing.AttrHTTPUrl] != "https://example.com/xml/verify" {
+		t.Errorf("Unexpected span: ended %v, %v", tracer.ended, tracer.attrs)
+	}
+}
+
+type counterCollector struct {
+	counters   map[string]metrics.Labels
+	histograms map[string]metrics.Labels
+}
+
+func (c *counterCollector) AddCounter(name string, _ float64, labels metrics.Labels) {
+	c.counters[name] = labels
+}
+
+func (c *counterCollector) ObserveHistogram(name string, _ float64, labels metrics.Labels) {
+	c.histograms[name] = labels
+}
+
+func TestClient_Request_Metrics(t *testing.T) {
+	m := &counterCollector{counters: map[string]metrics.Labels{}, histograms: map[string]metrics.Labels{}}
+	c := &Client{
+		BaseUrl: "https://node-a:14579/",
+		HTTPClient: &http.Client{Transport: &mockRoundTripper{roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(`{}`)), Header: make(http.Header)}, nil
+		}}},
+		Metrics: m,
+	}
+
+	if _, err := c.Request(context.Background(), http.MethodPost, "cms/sign", &bytes.Buffer{}); err != nil {
+		t.Fatalf("Expected no errors, got: %v", err)
+	}
+
+	l := m.counters[metrics.HTTPRequestsTotal]
+	if l["node"] != "https://node-a:14579/" || l["path"] != "/cms/sign" || l["method"] != "POST" || l["code"] != "200" {
+		t.Errorf("Unexpected counter labels: %v", l)
+	}
+	if _, ok := m.histograms[metrics.HTTPRequestDuration]; !ok {
+		t.Errorf("Expected latency to be observed")
+	}
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RequestDecorator amends every outgoing request, e.g. adds credentials expected by a gateway in front of NCANode.
+type RequestDecorator interface {
+	Decorate(req *http.Request) error
+}
+
+type DecoratorFunc func(req *http.Request) error
+
+func (f DecoratorFunc) Decorate(req *http.Request) error {
+	return f(req)
+}
+
+// invalidator is implemented by decorators holding credentials that may go stale, Client invalidates them on 401.
+type invalidator interface {
+	Invalidate()
+}
+
+func Headers(h http.Header) RequestDecorator {
+	h = h.Clone()
+
+	return DecoratorFunc(func(req *http.Request) error {
+		for k, v := range h {
+			req.Header[k] = append([]string(nil), v...)
+		}
+		return nil
+	})
+}
+
+func BasicAuth(username string, password string) RequestDecorator {
+	return DecoratorFunc(func(req *http.Request) error {
+		req.SetBasicAuth(username, password)
+		return nil
+	})
+}
+
+// TokenFunc fetches a fresh token, a zero expiresAt means the token is used until NCANode answers 401.
+type TokenFunc func(ctx context.Context) (token string, expiresAt time.Time, err error)
+
+const DefaultTokenSkew = 10 * time.Second
+
+// BearerToken sets the Authorization header, caching the token until Skew before it expires.
+type BearerToken struct {
+	Fetch TokenFunc
+	Skew  time.Duration
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+func NewBearerToken(fetch TokenFunc) *BearerToken {
+	return &BearerToken{Fetch: fetch, Skew: DefaultTokenSkew}
+}
+
+func (b *BearerToken) Decorate(req *http.Request) error {
+	token, err := b.Token(req.Context())
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	return nil
+}
+
+func (b *BearerToken) Token(ctx context.Context) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.token != `` && (b.expiresAt.IsZero() || time.Now().Add(b.Skew).Before(b.expiresAt)) {
+		return b.token, nil
+	}
+
+	if b.Fetch == nil {
+		return ``, errors.New(`api: bearer token has no Fetch func`)
+	}
+
+	token, expiresAt, err := b.Fetch(ctx)
+	if err != nil {
+		return ``, err
+	}
+	if token == `` {
+		return ``, errors.New(`api: empty bearer token`)
+	}
+
+	b.token, b.expiresAt = token, expiresAt
+
+	return token, nil
+}
+
+// Invalidate drops the cached token, the next request fetches a new one.
+func (b *BearerToken) Invalidate() {
+	b.mu.Lock()
+	b.token = ``
+	b.expiresAt = time.Time{}
+	b.mu.Unlock()
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClient_Request_Decorators(t *testing.T) {
+	var got *http.Request
+	c := &Client{
+		BaseUrl: "https://example.com",
+		HTTPClient: &http.Client{Transport: &mockRoundTripper{roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			got = req
+			return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(`{}`)), Header: make(http.Header)}, nil
+		}}},
+		Decorators: []RequestDecorator{
+			Headers(http.Header{"X-Tenant": {"acme"}}),
+			BasicAuth("user", "secret"),
+		},
+	}
+
+	if _, err := c.Request(context.Background(), http.MethodPost, "", bytes.NewBufferString(`{}`)); err != nil {
+		t.Fatalf("Expected no errors, got: %v", err)
+	}
+
+	if got.Header.Get("X-Tenant") != "acme" {
+		t.Errorf("Expected tenant header, got %q", got.Header.Get("X-Tenant"))
+	}
+	if u, p, ok := got.BasicAuth(); !ok || u != "user" || p != "secret" {
+		t.Errorf("Unexpected basic auth: %q %q %v", u, p, ok)
+	}
+	if got.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Expected Content-Type application/json, got %s", got.Header.Get("Content-Type"))
+	}
+}
+
+func TestClient_Request_DecoratorError(t *testing.T) {
+	decorateErr := errors.New("no token")
+	c := &Client{
+		BaseUrl: "https://example.com",
+		HTTPClient: &http.Client{Transport: &mockRoundTripper{roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			t.Errorf("Request must not be sent")
+			return nil, errors.New("unexpected")
+		}}},
+		Decorators: []RequestDecorator{DecoratorFunc(func(req *http.Request) error { return decorateErr })},
+	}
+
+	if _, err := c.Request(context.Background(), http.MethodPost, "", &bytes.Buffer{}); !errors.Is(err, decorateErr) {
+		t.Errorf("Expected decorator error, got: %v", err)
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	fetches := 0
+	token := NewBearerToken(func(ctx context.Context) (string, time.Time, error) {
+		fetches++
+		return fmt.Sprintf("token-%d", fetches), time.Time{}, nil
+	})
+
+	var auth []string
+	var bodies []string
+	c := &Client{
+		BaseUrl: "https://example.com",
+		HTTPClient: &http.Client{Transport: &mockRoundTripper{roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			h := req.Header.Get("Authorization")
+			b, _ := io.ReadAll(req.Body)
+			auth = append(auth, h)
+			bodies = append(bodies, string(b))
+			if h == "Bearer token-1" && len(auth) > 1 {
+				return &http.Response{StatusCode: http.StatusUnauthorized, Body: io.NopCloser(strings.NewReader(`expired`)), Header: make(http.Header)}, nil
+			}
+			return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(`{}`)), Header: make(http.Header)}, nil
+		}}},
+		Decorators: []RequestDecorator{token},
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.Request(context.Background(), http.MethodPost, "", bytes.NewBufferString(`{"a":1}`)); err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+	}
+
+	expected := []string{"Bearer token-1", "Bearer token-1", "Bearer token-2"}
+	if strings.Join(auth, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, auth)
+	}
+	for _, b := range bodies {
+		if b != `{"a":1}` {
+			t.Errorf("Expected payload to be resent, got %q", b)
+		}
+	}
+	if fetches != 2 {
+		t.Errorf("Expected 2 token fetches, got %d", fetches)
+	}
+}
+
+func TestBearerToken_Expiry(t *testing.T) {
+	fetches := 0
+	token := &BearerToken{Skew: time.Minute, Fetch: func(ctx context.Context) (string, time.Time, error) {
+		fetches++
+		return "token", time.Now().Add(30 * time.Second), nil
+	}}
+
+	for i := 0; i < 2; i++ {
+		if _, err := token.Token(context.Background()); err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+	}
+
+	if fetches != 2 {
+		t.Errorf("Expected token expiring within skew to be refetched, got %d fetches", fetches)
+	}
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// MaxErrorBodyLength limits how much of a non-2xx response body is kept in HTTPError.
+const MaxErrorBodyLength = 4096
+
+// HTTPError is returned by Client.Request for non-2xx responses, the full body is still returned as the result.
+type HTTPError struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	Truncated  bool
+}
+
+func newHTTPError(resp *http.Response, body []byte) *HTTPError {
+	e := &HTTPError{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       body,
+	}
+
+	if len(body) > MaxErrorBodyLength {
+		e.Body = body[:MaxErrorBodyLength]
+		e.Truncated = true
+	}
+
+	return e
+}
+
+func (e *HTTPError) Error() string {
+	s := fmt.Sprintf(`unexpected http status %d %s`, e.StatusCode, http.StatusText(e.StatusCode))
+	if len(e.Body) == 0 {
+		return s
+	}
+
+	body := string(e.Body)
+	if e.Truncated {
+		body += `...`
+	}
+
+	return s + `: ` + body
+}
@@ -0,0 +1,266 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/nbah1990/goncanode/metrics"
+	"github.com/nbah1990/goncanode/tracing"
+	"log/slog"
+	"net/http"
+	"sync"
+	"syscall"
+	"time"
+)
+
+type Strategy int
+
+const (
+	RoundRobin Strategy = iota
+	LeastInFlight
+)
+
+const (
+	DefaultFailureThreshold = 3
+	DefaultEjectionTime     = 30 * time.Second
+)
+
+// MultiClient spreads requests over several NCANode replicas and fails over to the next one on transient errors.
+// An endpoint failing FailureThreshold times in a row is ejected for EjectionTime, the first request after that re-probes it.
+type MultiClient struct {
+	Strategy         Strategy
+	FailureThreshold int
+	EjectionTime     time.Duration
+
+	mu        sync.Mutex
+	endpoints []*endpoint
+	next      int
+}
+
+type endpoint struct {
+	client       IClient
+	baseUrl      string
+	inFlight     int
+	failures     int
+	ejectedUntil time.Time
+}
+
+type EndpointStatus struct {
+	BaseUrl  string
+	Healthy  bool
+	InFlight int
+	Failures int
+}
+
+func NewMultiClient(httpClient *http.Client, baseUrls ...string) *MultiClient {
+	c := &MultiClient{
+		FailureThreshold: DefaultFailureThreshold,
+		EjectionTime:     DefaultEjectionTime,
+	}
+
+	for _, u := range baseUrls {
+		c.endpoints = append(c.endpoints, &endpoint{
+			client:  &Client{BaseUrl: u, HTTPClient: httpClient},
+			baseUrl: u,
+		})
+	}
+
+	return c
+}
+
+// Use adds decorators to every endpoint's client.
+func (c *MultiClient) Use(d ...RequestDecorator) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, e := range c.endpoints {
+		if client, ok := e.client.(*Client); ok {
+			client.Decorators = append(client.Decorators, d...)
+		}
+	}
+}
+
+// SetLogger sets the logger of every endpoint's client.
+func (c *MultiClient) SetLogger(l *slog.Logger) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, e := range c.endpoints {
+		if client, ok := e.client.(*Client); ok {
+			client.Logger = l
+		}
+	}
+}
+
+// SetTracer sets the tracer of every endpoint's client.
+func (c *MultiClient) SetTracer(t tracing.Tracer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, e := range c.endpoints {
+		if client, ok := e.client.(*Client); ok {
+			client.Tracer = t
+		}
+	}
+}
+
+// SetMetrics sets the collector of every endpoint's client.
+func (c *MultiClient) SetMetrics(m metrics.Collector) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, e := range c.endpoints {
+		if client, ok := e.client.(*Client); ok {
+			client.Metrics = m
+		}
+	}
+}
+
+func (c *MultiClient) Request(ctx context.Context, method string, url string, data *bytes.Buffer) (result []byte, err error) {
+	candidates := c.candidates()
+	if len(candidates) == 0 {
+		return nil, errors.New(`api: no endpoints configured`)
+	}
+
+	var payload []byte
+	if data != nil {
+		payload = data.Bytes()
+	}
+
+	for _, e := range candidates {
+		c.begin(e)
+		result, err = e.client.Request(ctx, method, url, bytes.NewBuffer(payload))
+		c.end(e, err)
+
+		if err == nil || ctx.Err() != nil || !canFailover(ctx, err) {
+			return
+		}
+	}
+
+	return
+}
+
+// Endpoints reports the passive health state of every endpoint.
+func (c *MultiClient) Endpoints() []EndpointStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	s := make([]EndpointStatus, 0, len(c.endpoints))
+	for _, e := range c.endpoints {
+		s = append(s, EndpointStatus{
+			BaseUrl:  e.baseUrl,
+			Healthy:  !now.Before(e.ejectedUntil),
+			InFlight: e.inFlight,
+			Failures: e.failures,
+		})
+	}
+
+	return s
+}
+
+// candidates orders healthy endpoints by the strategy, ejected ones are kept at the end as the last resort.
+func (c *MultiClient) candidates() []*endpoint {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := len(c.endpoints)
+	if n == 0 {
+		return nil
+	}
+
+	start := c.next % n
+	c.next++
+
+	now := time.Now()
+	var healthy, ejected []*endpoint
+	for i := 0; i < n; i++ {
+		e := c.endpoints[(start+i)%n]
+		if now.Before(e.ejectedUntil) {
+			ejected = append(ejected, e)
+		} else {
+			healthy = append(healthy, e)
+		}
+	}
+
+	if c.Strategy == LeastInFlight {
+		sortByInFlight(healthy)
+	}
+
+	return append(healthy, ejected...)
+}
+
+func sortByInFlight(s []*endpoint) {
+	for i := 1; i < len(s); i++ {
+		for j := i; j > 0 && s[j].inFlight < s[j-1].inFlight; j-- {
+			s[j], s[j-1] = s[j-1], s[j]
+		}
+	}
+}
+
+func (c *MultiClient) begin(e *endpoint) {
+	c.mu.Lock()
+	e.inFlight++
+	c.mu.Unlock()
+}
+
+func (c *MultiClient) end(e *endpoint, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e.inFlight--
+
+	if err == nil {
+		e.failures = 0
+		e.ejectedUntil = time.Time{}
+		return
+	}
+	if !isEndpointFailure(err) {
+		return
+	}
+
+	e.failures++
+
+	threshold := c.FailureThreshold
+	if threshold <= 0 {
+		threshold = DefaultFailureThreshold
+	}
+	ejection := c.EjectionTime
+	if ejection <= 0 {
+		ejection = DefaultEjectionTime
+	}
+
+	if e.failures >= threshold {
+		e.ejectedUntil = time.Now().Add(ejection)
+	}
+}
+
+// isEndpointFailure counts what IsBreakerFailure counts and 429 against a replica. Other 4xx are NCANode
+// rejecting the request itself, e.g. a wrong key password, and caller cancellation says nothing about the replica.
+func isEndpointFailure(err error) bool {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+
+	return IsBreakerFailure(err)
+}
+
+// canFailover allows any transient error for idempotent requests,
+// others only move on when the failed replica surely didn't process the request.
+func canFailover(ctx context.Context, err error) bool {
+	if !IsRetryable(err) {
+		return false
+	}
+
+	if IsIdempotent(ctx) {
+		return true
+	}
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusServiceUnavailable || httpErr.StatusCode == http.StatusTooManyRequests
+	}
+
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
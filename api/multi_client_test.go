@@ -0,0 +1,201 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+	"time"
+)
+
+type endpointMock struct {
+	name  string
+	err   error
+	calls int
+}
+
+func (m *endpointMock) Request(_ context.Context, _ string, _ string, _ *bytes.Buffer) ([]byte, error) {
+	m.calls++
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return []byte(m.name), nil
+}
+
+func newTestMultiClient(mocks ...*endpointMock) *MultiClient {
+	c := NewMultiClient(nil)
+	for _, m := range mocks {
+		c.endpoints = append(c.endpoints, &endpoint{client: m, baseUrl: m.name})
+	}
+
+	return c
+}
+
+func TestMultiClient_RoundRobin(t *testing.T) {
+	a, b, c := &endpointMock{name: "a"}, &endpointMock{name: "b"}, &endpointMock{name: "c"}
+	mc := newTestMultiClient(a, b, c)
+
+	var got string
+	for i := 0; i < 6; i++ {
+		r, err := mc.Request(context.Background(), "POST", "", &bytes.Buffer{})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		got += string(r)
+	}
+
+	if got != "abcabc" {
+		t.Errorf("Expected round-robin order abcabc, got %s", got)
+	}
+}
+
+func TestMultiClient_LeastInFlight(t *testing.T) {
+	a, b := &endpointMock{name: "a"}, &endpointMock{name: "b"}
+	mc := newTestMultiClient(a, b)
+	mc.Strategy = LeastInFlight
+	mc.endpoints[0].inFlight = 5
+
+	for i := 0; i < 4; i++ {
+		r, _ := mc.Request(context.Background(), "POST", "", &bytes.Buffer{})
+		if string(r) != "b" {
+			t.Errorf("Expected least loaded endpoint b, got %s", r)
+		}
+	}
+}
+
+func TestMultiClient_Failover(t *testing.T) {
+	refused := &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}
+	reset := &net.OpError{Op: "read", Err: syscall.ECONNRESET}
+
+	t.Run("Idempotent", func(t *testing.T) {
+		a, b := &endpointMock{name: "a", err: reset}, &endpointMock{name: "b"}
+		mc := newTestMultiClient(a, b)
+
+		r, err := mc.Request(WithIdempotent(context.Background()), "POST", "", &bytes.Buffer{})
+		if err != nil || string(r) != "b" {
+			t.Errorf("Expected failover to b, got %s, %v", r, err)
+		}
+	})
+
+	t.Run("NonIdempotentRefused", func(t *testing.T) {
+		a, b := &endpointMock{name: "a", err: refused}, &endpointMock{name: "b"}
+		mc := newTestMultiClient(a, b)
+
+		r, err := mc.Request(context.Background(), "POST", "", &bytes.Buffer{})
+		if err != nil || string(r) != "b" {
+			t.Errorf("Expected failover to b, got %s, %v", r, err)
+		}
+	})
+
+	t.Run("NonIdempotentReset", func(t *testing.T) {
+		a, b := &endpointMock{name: "a", err: reset}, &endpointMock{name: "b"}
+		mc := newTestMultiClient(a, b)
+
+		_, err := mc.Request(context.Background(), "POST", "", &bytes.Buffer{})
+		if err == nil || b.calls != 0 {
+			t.Errorf("Expected no failover after the request may have been processed, got %v, %d calls to b", err, b.calls)
+		}
+	})
+
+	t.Run("ClientError", func(t *testing.T) {
+		a, b := &endpointMock{name: "a", err: &HTTPError{StatusCode: http.StatusBadRequest}}, &endpointMock{name: "b"}
+		mc := newTestMultiClient(a, b)
+
+		_, err := mc.Request(WithIdempotent(context.Background()), "POST", "", &bytes.Buffer{})
+		if err == nil || b.calls != 0 {
+			t.Errorf("Expected no failover on 4xx, got %v, %d calls to b", err, b.calls)
+		}
+	})
+}
+
+func TestMultiClient_Ejection(t *testing.T) {
+	reset := &net.OpError{Op: "read", Err: syscall.ECONNRESET}
+	a, b := &endpointMock{name: "a", err: reset}, &endpointMock{name: "b"}
+	mc := newTestMultiClient(a, b)
+	mc.FailureThreshold = 2
+	mc.EjectionTime = 50 * time.Millisecond
+
+	ctx := WithIdempotent(context.Background())
+	for i := 0; i < 6; i++ {
+		if _, err := mc.Request(ctx, "POST", "", &bytes.Buffer{}); err != nil {
+			t.Fatalf("Expected failover to succeed, got %v", err)
+		}
+	}
+
+	if a.calls != 2 {
+		t.Errorf("Expected a to be ejected after 2 failures, got %d calls", a.calls)
+	}
+	if s := mc.Endpoints(); s[0].Healthy || !s[1].Healthy {
+		t.Errorf("Unexpected endpoint health: %+v", s)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	a.err = nil
+
+	for i := 0; i < 2; i++ {
+		_, _ = mc.Request(ctx, "POST", "", &bytes.Buffer{})
+	}
+
+	if a.calls != 3 {
+		t.Errorf("Expected a to be re-probed after ejection time, got %d calls", a.calls)
+	}
+	if s := mc.Endpoints(); !s[0].Healthy || s[0].Failures != 0 {
+		t.Errorf("Expected a to recover, got %+v", s[0])
+	}
+}
+
+func TestMultiClient_EjectionOnHTTPError(t *testing.T) {
+	t.Run("ClientError", func(t *testing.T) {
+		a, b := &endpointMock{name: "a", err: &HTTPError{StatusCode: http.StatusBadRequest}}, &endpointMock{name: "b"}
+		mc := newTestMultiClient(a, b)
+		mc.FailureThreshold = 2
+
+		for i := 0; i < 6; i++ {
+			_, _ = mc.Request(context.Background(), "POST", "", &bytes.Buffer{})
+		}
+
+		if a.calls != 3 {
+			t.Errorf("Expected a to stay in rotation on 4xx, got %d calls", a.calls)
+		}
+		if s := mc.Endpoints(); !s[0].Healthy || s[0].Failures != 0 {
+			t.Errorf("Expected a to stay healthy, got %+v", s[0])
+		}
+	})
+
+	t.Run("ServerError", func(t *testing.T) {
+		a, b := &endpointMock{name: "a", err: &HTTPError{StatusCode: http.StatusInternalServerError}}, &endpointMock{name: "b"}
+		mc := newTestMultiClient(a, b)
+		mc.FailureThreshold = 2
+
+		for i := 0; i < 6; i++ {
+			_, _ = mc.Request(context.Background(), "POST", "", &bytes.Buffer{})
+		}
+
+		if a.calls != 2 {
+			t.Errorf("Expected a to be ejected after 2 errors, got %d calls", a.calls)
+		}
+		if s := mc.Endpoints(); s[0].Healthy || s[0].Failures != 2 {
+			t.Errorf("Expected a to be ejected, got %+v", s[0])
+		}
+	})
+}
+
+func TestNewMultiClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`ok`))
+	}))
+	defer srv.Close()
+
+	mc := NewMultiClient(srv.Client(), "http://127.0.0.1:1", srv.URL)
+
+	for i := 0; i < 2; i++ {
+		r, err := mc.Request(WithIdempotent(context.Background()), "GET", "/path", &bytes.Buffer{})
+		if err != nil || string(r) != "ok" {
+			t.Errorf("Expected response from the live endpoint, got %s, %v", r, err)
+		}
+	}
+}
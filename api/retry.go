@@ -0,0 +1,142 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+type idempotentKey struct{}
+
+// WithIdempotent marks the request made with ctx as safe to repeat, handlers mark verify, extract and info calls.
+func WithIdempotent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, idempotentKey{}, true)
+}
+
+func IsIdempotent(ctx context.Context) bool {
+	v, _ := ctx.Value(idempotentKey{}).(bool)
+	return v
+}
+
+type RetryPolicy struct {
+	// MaxAttempts counts the first attempt too, values below 2 disable retries.
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	// Jitter randomizes every backoff by ±Jitter share of it, 0.2 means ±20%.
+	Jitter float64
+	// Retryable decides whether err is transient, IsRetryable is used when nil.
+	Retryable func(err error) bool
+	// RetryNonIdempotent enables retries of requests not marked with WithIdempotent, e.g. signing.
+	RetryNonIdempotent bool
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     2 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+}
+
+// IsRetryable reports connection failures and gateway/overload statuses as transient.
+// Cancellation and deadline errors of the caller's context are never retried.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		switch httpErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return true
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// Backoff returns the delay before the given retry, attempt starts with 1.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	d := float64(p.InitialBackoff)
+	if p.Multiplier > 1 {
+		d *= math.Pow(p.Multiplier, float64(attempt-1))
+	}
+
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+
+	if p.Jitter > 0 {
+		d += d * p.Jitter * (2*rand.Float64() - 1)
+	}
+
+	if d < 0 {
+		return 0
+	}
+
+	return time.Duration(d)
+}
+
+type RetryClient struct {
+	Client IClient
+	Policy RetryPolicy
+}
+
+func (c *RetryClient) Request(ctx context.Context, method string, url string, data *bytes.Buffer) (result []byte, err error) {
+	if c.Policy.MaxAttempts < 2 || !(c.Policy.RetryNonIdempotent || IsIdempotent(ctx)) {
+		return c.Client.Request(ctx, method, url, data)
+	}
+
+	var payload []byte
+	if data != nil {
+		payload = data.Bytes()
+	}
+
+	retryable := c.Policy.Retryable
+	if retryable == nil {
+		retryable = IsRetryable
+	}
+
+	for attempt := 1; ; attempt++ {
+		result, err = c.Client.Request(ctx, method, url, bytes.NewBuffer(payload))
+		if err == nil || attempt >= c.Policy.MaxAttempts || !retryable(err) {
+			return
+		}
+
+		wait := c.Policy.Backoff(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(wait).After(deadline) {
+			return
+		}
+
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+)
+
+type mockClient struct {
+	errs   []error
+	calls  int
+	bodies []string
+}
+
+func (m *mockClient) Request(_ context.Context, _ string, _ string, data *bytes.Buffer) ([]byte, error) {
+	m.calls++
+	m.bodies = append(m.bodies, data.String())
+
+	if len(m.errs) >= m.calls {
+		if err := m.errs[m.calls-1]; err != nil {
+			return nil, err
+		}
+	}
+
+	return []byte(`ok`), nil
+}
+
+func testRetryPolicy() RetryPolicy {
+	p := DefaultRetryPolicy()
+	p.InitialBackoff = time.Millisecond
+	p.MaxBackoff = 2 * time.Millisecond
+
+	return p
+}
+
+func TestRetryClient_Request(t *testing.T) {
+	connReset := &net.OpError{Op: "read", Err: syscall.ECONNRESET}
+
+	t.Run("RetriesIdempotent", func(t *testing.T) {
+		m := &mockClient{errs: []error{connReset, &HTTPError{StatusCode: http.StatusServiceUnavailable}}}
+		c := &RetryClient{Client: m, Policy: testRetryPolicy()}
+
+		result, err := c.Request(WithIdempotent(context.Background()), "POST", "/path", bytes.NewBufferString("payload"))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if string(result) != "ok" || m.calls != 3 {
+			t.Errorf("Expected success on third attempt, got %s after %d calls", result, m.calls)
+		}
+		for _, b := range m.bodies {
+			if b != "payload" {
+				t.Errorf("Expected payload resent on every attempt, got %q", b)
+			}
+		}
+	})
+
+	t.Run("NonIdempotentNotRetried", func(t *testing.T) {
+		m := &mockClient{errs: []error{connReset}}
+		c := &RetryClient{Client: m, Policy: testRetryPolicy()}
+
+		_, err := c.Request(context.Background(), "POST", "/path", &bytes.Buffer{})
+		if !errors.Is(err, syscall.ECONNRESET) || m.calls != 1 {
+			t.Errorf("Expected single failed attempt, got %v after %d calls", err, m.calls)
+		}
+	})
+
+	t.Run("NonIdempotentOptIn", func(t *testing.T) {
+		m := &mockClient{errs: []error{connReset}}
+		p := testRetryPolicy()
+		p.RetryNonIdempotent = true
+		c := &RetryClient{Client: m, Policy: p}
+
+		_, err := c.Request(context.Background(), "POST", "/path", &bytes.Buffer{})
+		if err != nil || m.calls != 2 {
+			t.Errorf("Expected success on second attempt, got %v after %d calls", err, m.calls)
+		}
+	})
+
+	t.Run("MaxAttempts", func(t *testing.T) {
+		m := &mockClient{errs: []error{connReset, connReset, connReset, connReset}}
+		c := &RetryClient{Client: m, Policy: testRetryPolicy()}
+
+		_, err := c.Request(WithIdempotent(context.Background()), "POST", "/path", &bytes.Buffer{})
+		if err == nil || m.calls != 3 {
+			t.Errorf("Expected failure after 3 attempts, got %v after %d calls", err, m.calls)
+		}
+	})
+
+	t.Run("NotRetryable", func(t *testing.T) {
+		m := &mockClient{errs: []error{&HTTPError{StatusCode: http.StatusBadRequest}}}
+		c := &RetryClient{Client: m, Policy: testRetryPolicy()}
+
+		_, err := c.Request(WithIdempotent(context.Background()), "POST", "/path", &bytes.Buffer{})
+		if err == nil || m.calls != 1 {
+			t.Errorf("Expected single failed attempt, got %v after %d calls", err, m.calls)
+		}
+	})
+
+	t.Run("RespectsDeadline", func(t *testing.T) {
+		m := &mockClient{errs: []error{connReset, connReset}}
+		p := testRetryPolicy()
+		p.InitialBackoff = time.Second
+		p.MaxBackoff = time.Second
+		c := &RetryClient{Client: m, Policy: p}
+
+		ctx, cancel := context.WithTimeout(WithIdempotent(context.Background()), 50*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		_, err := c.Request(ctx, "POST", "/path", &bytes.Buffer{})
+		if err == nil || m.calls != 1 {
+			t.Errorf("Expected single failed attempt, got %v after %d calls", err, m.calls)
+		}
+		if time.Since(start) > 500*time.Millisecond {
+			t.Errorf("Expected no backoff past the deadline, took %s", time.Since(start))
+		}
+	})
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"Nil", nil, false},
+		{"Canceled", context.Canceled, false},
+		{"DeadlineExceeded", context.DeadlineExceeded, false},
+		{"ConnectionRefused", &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, true},
+		{"BadGateway", &HTTPError{StatusCode: http.StatusBadGateway}, true},
+		{"TooManyRequests", &HTTPError{StatusCode: http.StatusTooManyRequests}, true},
+		{"InternalServerError", &HTTPError{StatusCode: http.StatusInternalServerError}, false},
+		{"Other", errors.New("other"), false},
+	}
+
+	for _, tt := range tests {
+		if got := IsRetryable(tt.err); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond, Multiplier: 2}
+
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond}
+	for i, w := range want {
+		if got := p.Backoff(i + 1); got != w {
+			t.Errorf("Attempt %d: expected %s, got %s", i+1, w, got)
+		}
+	}
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := p.Backoff(1); got < 50*time.Millisecond || got > 150*time.Millisecond {
+			t.Fatalf("Expected jittered backoff within ±50%%, got %s", got)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// TLSConfig describes the TLS connection to NCANode or to the HTTPS proxy in front of it.
+// PEM bytes take precedence over the corresponding files.
+type TLSConfig struct {
+	CAFile string
+	CAPEM  []byte
+
+	CertFile string
+	KeyFile  string
+	CertPEM  []byte
+	KeyPEM   []byte
+
+	ServerName string
+	// MinVersion takes tls.VersionTLS1x constants, TLS 1.2 is required when zero.
+	MinVersion uint16
+}
+
+func (c *TLSConfig) Build() (*tls.Config, error) {
+	tc := &tls.Config{
+		ServerName: c.ServerName,
+		MinVersion: c.MinVersion,
+	}
+
+	if tc.MinVersion == 0 {
+		tc.MinVersion = tls.VersionTLS12
+	}
+
+	ca, err := pemOrFile(c.CAPEM, c.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf(`tls: can't read ca bundle: %w`, err)
+	}
+	if ca != nil {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, errors.New(`tls: no certificates found in ca bundle`)
+		}
+		tc.RootCAs = pool
+	}
+
+	cert, err := pemOrFile(c.CertPEM, c.CertFile)
+	if err != nil {
+		return nil, fmt.Errorf(`tls: can't read client certificate: %w`, err)
+	}
+	key, err := pemOrFile(c.KeyPEM, c.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf(`tls: can't read client key: %w`, err)
+	}
+
+	if (cert == nil) != (key == nil) {
+		return nil, errors.New(`tls: client certificate and key must be set together`)
+	}
+	if cert != nil {
+		pair, err := tls.X509KeyPair(cert, key)
+		if err != nil {
+			return nil, fmt.Errorf(`tls: invalid client certificate: %w`, err)
+		}
+		tc.Certificates = []tls.Certificate{pair}
+	}
+
+	return tc, nil
+}
+
+func pemOrFile(pem []byte, file string) ([]byte, error) {
+	if pem != nil {
+		return pem, nil
+	}
+
+	if file == `` {
+		return nil, nil
+	}
+
+	return os.ReadFile(file)
+}
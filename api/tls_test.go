@@ -0,0 +1,154 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testCertificate(t *testing.T, name string, client bool, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, []byte, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{name},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	if parent == nil {
+		tpl.IsCA = true
+		tpl.BasicConstraintsValid = true
+		tpl.KeyUsage = x509.KeyUsageCertSign
+		parent, parentKey = tpl, key
+	} else if client {
+		tpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	} else {
+		tpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert, key,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+}
+
+func TestTLSConfig_Build(t *testing.T) {
+	_, _, caPem, _ := testCertificate(t, "ca", false, nil, nil)
+
+	t.Run("Defaults", func(t *testing.T) {
+		tc, err := (&TLSConfig{ServerName: "ncanode"}).Build()
+		if err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+		if tc.MinVersion != tls.VersionTLS12 || tc.ServerName != "ncanode" || tc.RootCAs != nil || tc.Certificates != nil {
+			t.Errorf("Unexpected config: %+v", tc)
+		}
+	})
+
+	t.Run("CAFile", func(t *testing.T) {
+		f := filepath.Join(t.TempDir(), "ca.pem")
+		if err := os.WriteFile(f, caPem, 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		tc, err := (&TLSConfig{CAFile: f, MinVersion: tls.VersionTLS13}).Build()
+		if err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+		if tc.RootCAs == nil || tc.MinVersion != tls.VersionTLS13 {
+			t.Errorf("Unexpected config: %+v", tc)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		for name, c := range map[string]*TLSConfig{
+			"MissingFile":    {CAFile: filepath.Join(t.TempDir(), "missing.pem")},
+			"InvalidCA":      {CAPEM: []byte("not a pem")},
+			"CertWithoutKey": {CertPEM: caPem},
+			"InvalidPair":    {CertPEM: caPem, KeyPEM: []byte("not a pem")},
+		} {
+			if _, err := c.Build(); err == nil {
+				t.Errorf("%s: expected error, got nil", name)
+			}
+		}
+	})
+}
+
+func TestClient_Request_MutualTLS(t *testing.T) {
+	ca, caKey, caPem, _ := testCertificate(t, "ca", false, nil, nil)
+	_, _, serverPem, serverKeyPem := testCertificate(t, "ncanode.local", false, ca, caKey)
+	_, _, clientPem, clientKeyPem := testCertificate(t, "client", true, ca, caKey)
+
+	serverCert, err := tls.X509KeyPair(serverPem, serverKeyPem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{serverCert}, ClientCAs: pool, ClientAuth: tls.RequireAndVerifyClientCert}
+	srv.StartTLS()
+	defer srv.Close()
+
+	request := func(c TLSConfig) ([]byte, error) {
+		tc, err := c.Build()
+		if err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+		cfg := DefaultTransportConfig()
+		cfg.TLSClientConfig = tc
+		client := &Client{BaseUrl: srv.URL, HTTPClient: NewHTTPClient(cfg)}
+
+		return client.Request(context.Background(), http.MethodGet, "/", &bytes.Buffer{})
+	}
+
+	result, err := request(TLSConfig{CAPEM: caPem, CertPEM: clientPem, KeyPEM: clientKeyPem, ServerName: "ncanode.local"})
+	if err != nil {
+		t.Fatalf("Expected no errors, got: %v", err)
+	}
+	if string(result) != "client" {
+		t.Errorf("Expected client certificate to be presented, got: %s", result)
+	}
+
+	if _, err := request(TLSConfig{CAPEM: caPem, ServerName: "ncanode.local"}); err == nil {
+		t.Errorf("Expected handshake error without client certificate")
+	}
+
+	if _, err := request(TLSConfig{CAPEM: caPem, CertPEM: clientPem, KeyPEM: clientKeyPem, ServerName: "other.local"}); err == nil {
+		t.Errorf("Expected verification error for wrong server name")
+	}
+}
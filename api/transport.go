@@ -0,0 +1,62 @@
+package api
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+)
+
+// defaultHTTPClient is shared by every Client without HTTPClient so connections are pooled between requests.
+var defaultHTTPClient = &http.Client{}
+
+type TransportConfig struct {
+	DialTimeout           time.Duration
+	KeepAlive             time.Duration
+	TLSHandshakeTimeout   time.Duration
+	IdleConnTimeout       time.Duration
+	ResponseHeaderTimeout time.Duration
+	MaxIdleConns          int
+	MaxIdleConnsPerHost   int
+	MaxConnsPerHost       int
+	TLSClientConfig       *tls.Config
+}
+
+// DefaultTransportConfig keeps more idle connections per host than net/http does, NCANode is usually the only host.
+func DefaultTransportConfig() TransportConfig {
+	return TransportConfig{
+		DialTimeout:         5 * time.Second,
+		KeepAlive:           30 * time.Second,
+		TLSHandshakeTimeout: 5 * time.Second,
+		IdleConnTimeout:     90 * time.Second,
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 32,
+	}
+}
+
+func NewTransport(c TransportConfig) *http.Transport {
+	d := &net.Dialer{
+		Timeout:   c.DialTimeout,
+		KeepAlive: c.KeepAlive,
+	}
+
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           d.DialContext,
+		ForceAttemptHTTP2:     true,
+		TLSHandshakeTimeout:   c.TLSHandshakeTimeout,
+		IdleConnTimeout:       c.IdleConnTimeout,
+		ResponseHeaderTimeout: c.ResponseHeaderTimeout,
+		MaxIdleConns:          c.MaxIdleConns,
+		MaxIdleConnsPerHost:   c.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       c.MaxConnsPerHost,
+		ExpectContinueTimeout: time.Second,
+		TLSClientConfig:       c.TLSClientConfig,
+	}
+}
+
+func NewHTTPClient(c TransportConfig) *http.Client {
+	return &http.Client{
+		Transport: NewTransport(c),
+	}
+}
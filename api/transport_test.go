@@ -0,0 +1,33 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestNewTransport(t *testing.T) {
+	c := DefaultTransportConfig()
+	c.MaxIdleConnsPerHost = 64
+	c.MaxConnsPerHost = 128
+	c.IdleConnTimeout = time.Minute
+	c.ResponseHeaderTimeout = 3 * time.Second
+
+	tr := NewTransport(c)
+	if tr.MaxIdleConnsPerHost != 64 || tr.MaxConnsPerHost != 128 || tr.MaxIdleConns != c.MaxIdleConns {
+		t.Errorf("Unexpected pool sizes: %d, %d, %d", tr.MaxIdleConnsPerHost, tr.MaxConnsPerHost, tr.MaxIdleConns)
+	}
+	if tr.IdleConnTimeout != time.Minute || tr.TLSHandshakeTimeout != c.TLSHandshakeTimeout || tr.ResponseHeaderTimeout != 3*time.Second {
+		t.Errorf("Unexpected timeouts: %s, %s, %s", tr.IdleConnTimeout, tr.TLSHandshakeTimeout, tr.ResponseHeaderTimeout)
+	}
+	if tr.DialContext == nil {
+		t.Errorf("Expected dialer with configured timeouts")
+	}
+}
+
+func TestNewHTTPClient(t *testing.T) {
+	hc := NewHTTPClient(DefaultTransportConfig())
+	if _, ok := hc.Transport.(*http.Transport); !ok {
+		t.Errorf("Expected *http.Transport, got %T", hc.Transport)
+	}
+}
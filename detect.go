@@ -0,0 +1,233 @@
+package goncanode
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/nbah1990/goncanode/api"
+	"github.com/nbah1990/goncanode/entities"
+	"github.com/nbah1990/goncanode/types"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+type v3ActuatorInfoResponse struct {
+	Build struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+		Time    string `json:"time"`
+	} `json:"build"`
+}
+
+type nodeInfoResponse struct {
+	Version string `json:"version"`
+	Name    string `json:"name"`
+	Result  *struct {
+		Version  string `json:"version"`
+		Name     string `json:"name"`
+		DateTime string `json:"datetime"`
+	} `json:"result"`
+}
+
+// Detector probes NCANode once per service url and remembers the result for TTL (forever when TTL is zero).
+type Detector struct {
+	TTL time.Duration
+
+	mu    sync.Mutex
+	cache map[string]detectedNode
+}
+
+type detectedNode struct {
+	info      entities.NodeInfo
+	expiresAt time.Time
+}
+
+// Detect probes the service configured in o and returns the handler built by New for its version, o.Version is ignored.
+func Detect(ctx context.Context, o entities.Options) (Handler, entities.NodeInfo, error) {
+	return (&Detector{}).Detect(ctx, o)
+}
+
+func (d *Detector) Detect(ctx context.Context, o entities.Options) (Handler, entities.NodeInfo, error) {
+	o.Version = nil
+	err := ValidateOptions(o)
+	if err != nil {
+		return nil, entities.NodeInfo{}, err
+	}
+
+	// the probe and the handler share one http client and its connection pool
+	o.HTTPClient, err = newHTTPClient(o)
+	if err != nil {
+		return nil, entities.NodeInfo{}, err
+	}
+	o.TLS = nil
+
+	info, err := d.info(ctx, withDefaults(o))
+	if err != nil {
+		return nil, info, err
+	}
+
+	o.Version = &info.Version
+
+	h, err := New(o)
+	if err != nil {
+		return nil, info, err
+	}
+
+	return h, info, nil
+}
+
+func (d *Detector) info(ctx context.Context, o entities.Options) (entities.NodeInfo, error) {
+	key := detectKey(o)
+
+	d.mu.Lock()
+	c, ok := d.cache[key]
+	d.mu.Unlock()
+
+	if ok && (c.expiresAt.IsZero() || time.Now().Before(c.expiresAt)) {
+		return c.info, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, o.Timeout)
+	defer cancel()
+
+	a, err := newApiClient(o)
+	if err != nil {
+		return entities.NodeInfo{}, err
+	}
+
+	info, err := DetectVersion(ctx, a)
+	if err != nil {
+		return info, err
+	}
+
+	c = detectedNode{info: info}
+	if d.TTL > 0 {
+		c.expiresAt = time.Now().Add(d.TTL)
+	}
+
+	d.mu.Lock()
+	if d.cache == nil {
+		d.cache = make(map[string]detectedNode)
+	}
+	d.cache[key] = c
+	d.mu.Unlock()
+
+	return info, nil
+}
+
+// Forget drops the cached result for serviceUrl so the next Detect probes the service again,
+// results for ServiceUrls listing serviceUrl are dropped too.
+func (d *Detector) Forget(serviceUrl string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for key := range d.cache {
+		for _, u := range strings.Fields(key) {
+			if u == serviceUrl {
+				delete(d.cache, key)
+				break
+			}
+		}
+	}
+}
+
+// detectKey identifies the services of o, ServiceUrl and ServiceUrls are never both set.
+func detectKey(o entities.Options) string {
+	if len(o.ServiceUrls) > 0 {
+		return strings.Join(o.ServiceUrls, ` `)
+	}
+
+	return o.ServiceUrl
+}
+
+// DetectVersion asks the v3 actuator endpoint first and falls back to the NODE.info method of v1 and v2.
+func DetectVersion(ctx context.Context, c api.IClient) (result entities.NodeInfo, err error) {
+	ctx = api.WithIdempotent(ctx)
+
+	result, v3Err := detectV3(ctx, c)
+	if v3Err == nil {
+		return result, nil
+	}
+
+	result, rpcErr := detectRpc(ctx, c)
+	if rpcErr == nil {
+		return result, nil
+	}
+
+	return result, fmt.Errorf(`DetectVersion: can't identify NCANode: %w`, errors.Join(v3Err, rpcErr))
+}
+
+func detectV3(ctx context.Context, c api.IClient) (result entities.NodeInfo, err error) {
+	b, err := c.Request(ctx, http.MethodGet, `/actuator/info`, &bytes.Buffer{})
+	if err != nil {
+		return result, fmt.Errorf(`actuator info: %w`, err)
+	}
+
+	var respStruct v3ActuatorInfoResponse
+	err = json.Unmarshal(b, &respStruct)
+	if err != nil {
+		return result, fmt.Errorf(`actuator info: %w`, err)
+	}
+
+	result, err = nodeInfo(respStruct.Build.Version, respStruct.Build.Name, respStruct.Build.Time)
+	if err != nil {
+		return result, fmt.Errorf(`actuator info: %w`, err)
+	}
+
+	return result, nil
+}
+
+func detectRpc(ctx context.Context, c api.IClient) (result entities.NodeInfo, err error) {
+	rs, err := json.Marshal(rpcRequest{Version: string(types.NCAnodeV10), Method: `NODE.info`})
+	if err != nil {
+		return result, fmt.Errorf(`NODE.info: %w`, err)
+	}
+
+	b, err := c.Request(ctx, http.MethodPost, ``, bytes.NewBuffer(rs))
+	if err != nil {
+		return result, fmt.Errorf(`NODE.info: %w`, err)
+	}
+
+	var respStruct nodeInfoResponse
+	err = json.Unmarshal(b, &respStruct)
+	if err != nil {
+		return result, fmt.Errorf(`NODE.info: %w`, err)
+	}
+
+	if r := respStruct.Result; r != nil {
+		result, err = nodeInfo(r.Version, r.Name, r.DateTime)
+	} else {
+		result, err = nodeInfo(respStruct.Version, respStruct.Name, ``)
+	}
+	if err != nil {
+		return result, fmt.Errorf(`NODE.info: %w`, err)
+	}
+
+	return result, nil
+}
+
+func nodeInfo(serverVersion string, name string, buildTime string) (result entities.NodeInfo, err error) {
+	result = entities.NodeInfo{
+		ServerVersion: serverVersion,
+		Name:          name,
+		BuildTime:     buildTime,
+	}
+
+	major, _, _ := strings.Cut(strings.TrimPrefix(serverVersion, `v`), `.`)
+	switch major {
+	case `1`:
+		result.Version = types.NCAnodeV10
+	case `2`:
+		result.Version = types.NCAnodeV20
+	case `3`:
+		result.Version = types.NCAnodeV30
+	default:
+		return result, fmt.Errorf(`%w: server version %q`, ErrUnknownVersion, serverVersion)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,135 @@
+package goncanode
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nbah1990/goncanode/entities"
+	"github.com/nbah1990/goncanode/types"
+)
+
+type mockDetectClient struct {
+	responses map[string][]byte
+}
+
+func (m *mockDetectClient) Request(_ context.Context, _ string, url string, _ *bytes.Buffer) ([]byte, error) {
+	if r, ok := m.responses[url]; ok {
+		return r, nil
+	}
+
+	return nil, errors.New("not found")
+}
+
+func TestDetectVersion(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses map[string][]byte
+		want      types.Version
+		server    string
+	}{
+		{
+			name:      "V3",
+			responses: map[string][]byte{"/actuator/info": []byte(`{"build":{"name":"NCANode","version":"3.2.3","time":"2023-01-01T00:00:00Z"}}`)},
+			want:      types.NCAnodeV30,
+			server:    "3.2.3",
+		},
+		{
+			name:      "V2",
+			responses: map[string][]byte{"": []byte(`{"status":0,"message":"","version":"2.3.0","name":"NCANode"}`)},
+			want:      types.NCAnodeV20,
+			server:    "2.3.0",
+		},
+		{
+			name:      "V1",
+			responses: map[string][]byte{"": []byte(`{"status":0,"message":"","result":{"version":"1.3.2","name":"NCANode"}}`)},
+			want:      types.NCAnodeV10,
+			server:    "1.3.2",
+		},
+		{
+			name: "V1BehindHtmlActuator",
+			responses: map[string][]byte{
+				"/actuator/info": []byte(`<html></html>`),
+				"":               []byte(`{"status":0,"message":"","result":{"version":"1.3.2"}}`),
+			},
+			want:   types.NCAnodeV10,
+			server: "1.3.2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := DetectVersion(context.Background(), &mockDetectClient{responses: tt.responses})
+			if err != nil {
+				t.Fatalf("Expected no errors, got: %v", err)
+			}
+			if info.Version != tt.want || info.ServerVersion != tt.server {
+				t.Errorf("Expected version %s (%s), got: %+v", tt.want, tt.server, info)
+			}
+		})
+	}
+
+	t.Run("Unknown", func(t *testing.T) {
+		client := &mockDetectClient{responses: map[string][]byte{
+			"": []byte(`{"status":0,"message":"","result":{"version":"9.0.0"}}`),
+		}}
+
+		_, err := DetectVersion(context.Background(), client)
+		if err == nil {
+			t.Errorf("Expected detection error, got nil")
+		}
+	})
+
+	t.Run("Unreachable", func(t *testing.T) {
+		_, err := DetectVersion(context.Background(), &mockDetectClient{})
+		if err == nil {
+			t.Errorf("Expected detection error, got nil")
+		}
+	})
+}
+
+func TestDetector_Detect(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		if r.URL.Path == "/actuator/info" {
+			_, _ = w.Write([]byte(`{"build":{"version":"3.1.0"}}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	d := &Detector{}
+	options := entities.Options{ServiceUrl: srv.URL, P12base64: "base64string", P12pass: "password", Timeout: 5 * time.Second}
+
+	for i := 0; i < 3; i++ {
+		handler, info, err := d.Detect(context.Background(), options)
+		if err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+		if _, ok := handler.(*NCANodeV3Handler); !ok {
+			t.Errorf("Expected handler type *NCANodeV3Handler, got %T", handler)
+		}
+		if info.Version != types.NCAnodeV30 {
+			t.Errorf("Expected version 3.0, got: %s", info.Version)
+		}
+	}
+
+	if atomic.LoadInt32(&hits) != 1 {
+		t.Errorf("Expected single probe, got: %d", hits)
+	}
+
+	d.Forget(srv.URL)
+	if _, _, err := d.Detect(context.Background(), options); err != nil {
+		t.Fatalf("Expected no errors, got: %v", err)
+	}
+	if atomic.LoadInt32(&hits) != 2 {
+		t.Errorf("Expected probe after Forget, got: %d", hits)
+	}
+}
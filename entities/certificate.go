@@ -0,0 +1,47 @@
+package entities
+
+import (
+	"github.com/nbah1990/goncanode/types"
+	"time"
+)
+
+type Certificate struct {
+	Valid        bool              `json:"valid"`
+	SerialNumber string            `json:"serialNumber"`
+	NotBefore    time.Time         `json:"notBefore"`
+	NotAfter     time.Time         `json:"notAfter"`
+	KeyUsage     string            `json:"keyUsage"`
+	KeyUser      []string          `json:"keyUser"`
+	SignAlg      string            `json:"signAlg"`
+	Policies     []string          `json:"policies"`
+	Subject      DistinguishedName `json:"subject"`
+	Issuer       DistinguishedName `json:"issuer"`
+	Certificate  string            `json:"certificate"`
+	Revocations  []Revocation      `json:"revocations"`
+}
+
+type KeyAlias struct {
+	Alias    string `json:"alias"`
+	KeyUsage string `json:"keyUsage"`
+}
+
+type DistinguishedName struct {
+	DN           string `json:"dn"`
+	CommonName   string `json:"commonName"`
+	LastName     string `json:"lastName"`
+	SurName      string `json:"surName"`
+	Email        string `json:"email"`
+	Organization string `json:"organization"`
+	IIN          string `json:"iin"`
+	BIN          string `json:"bin"`
+	Country      string `json:"country"`
+	Locality     string `json:"locality"`
+	State        string `json:"state"`
+}
+
+type Revocation struct {
+	By             types.RevocationCheck `json:"by"`
+	Revoked        bool                  `json:"revoked"`
+	RevocationTime *time.Time            `json:"revocationTime"`
+	Reason         string                `json:"reason"`
+}
@@ -0,0 +1,20 @@
+package entities
+
+import "time"
+
+type CmsResult struct {
+	Der    []byte `json:"-"`
+	Base64 string `json:"cms"`
+}
+
+type CmsVerifyResult struct {
+	Valid   bool        `json:"valid"`
+	Signers []CmsSigner `json:"signers"`
+}
+
+type CmsSigner struct {
+	Certificate Certificate   `json:"certificate"`
+	Chain       []Certificate `json:"chain"`
+	ChainValid  bool          `json:"chainValid"`
+	SigningTime *time.Time    `json:"signingTime"`
+}
@@ -0,0 +1,28 @@
+package entities
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Key is the PKCS#12 container used for signing and the password protecting it.
+type Key struct {
+	P12base64 string
+	Password  string
+	// Alias selects the key inside a container holding several, NCANode picks one itself when empty.
+	Alias string
+}
+
+func (k Key) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String(`p12`, `[REDACTED]`),
+		slog.String(`password`, `[REDACTED]`),
+		slog.String(`alias`, k.Alias),
+	)
+}
+
+// KeyProvider is asked for the key on every call that needs one, so rotated keys are picked up without a restart.
+// Implementations must be safe for concurrent use, see the keys package.
+type KeyProvider interface {
+	Key(ctx context.Context) (Key, error)
+}
@@ -0,0 +1,10 @@
+package entities
+
+import "github.com/nbah1990/goncanode/types"
+
+type NodeInfo struct {
+	Version       types.Version `json:"version"`
+	ServerVersion string        `json:"serverVersion"`
+	Name          string        `json:"name"`
+	BuildTime     string        `json:"buildTime"`
+}
@@ -1,7 +1,13 @@
 package entities
 
 import (
+	"github.com/nbah1990/goncanode/api"
+	"github.com/nbah1990/goncanode/metrics"
+	"github.com/nbah1990/goncanode/tracing"
 	"github.com/nbah1990/goncanode/types"
+	"io"
+	"log/slog"
+	"net/http"
 	"time"
 )
 
@@ -11,5 +17,46 @@ type Options struct {
 	P12pass    string
 	Timeout    time.Duration
 
+	// P12File and P12Reader load the container once at construction instead of P12base64.
+	P12File   string
+	P12Reader io.Reader
+	// KeyAlias selects the signing key of a container holding several, it must be present in one loaded locally.
+	// goncanode.SignKeyAlias overrides it per call.
+	KeyAlias string
+	// VerifyKey checks at construction that P12pass opens P12base64 too, containers from P12File and P12Reader are always checked.
+	VerifyKey bool
+
+	// Keys supplies the key on every call instead of P12base64 and P12pass, see the keys package.
+	Keys KeyProvider
+
 	Version *types.Version
+	// Tsp timestamps every CMS signature, NCANode v3 only.
+	Tsp *Tsp
+
+	// ServiceUrls lists NCANode replicas served through api.MultiClient, ServiceUrl must be left empty then.
+	ServiceUrls []string
+	Balancing   api.Strategy
+
+	// HTTPClient is used as is when set, otherwise one client is built from Transport
+	// (api.DefaultTransportConfig when nil) and shared by every request of the handler.
+	HTTPClient *http.Client
+	Transport  *api.TransportConfig
+	// TLS configures the transport built from Transport, it can't be combined with HTTPClient.
+	TLS *api.TLSConfig
+	// Decorators amend every request, e.g. api.Headers, api.BasicAuth or api.NewBearerToken for a gateway in front of NCANode.
+	Decorators []api.RequestDecorator
+	// Logger gets a record per handler call and per http exchange, key material and passwords are always redacted.
+	Logger *slog.Logger
+	// Tracer gets a span per handler call and per http exchange, see the tracing package.
+	Tracer tracing.Tracer
+	// Metrics gets call and http exchange counts and latencies, metrics.NewPrometheus serves them over http.
+	Metrics metrics.Collector
+
+	// CircuitBreaker guards the client built from these options through CircuitBreaker.Wrap, its Client is left alone
+	// so one breaker may be shared by several handlers.
+	// Keep the pointer to report CircuitBreaker.State in health checks.
+	CircuitBreaker *api.CircuitBreaker
+
+	// Retry enables api.RetryClient, only idempotent calls are retried unless RetryNonIdempotent is set.
+	Retry *api.RetryPolicy
 }
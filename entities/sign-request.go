@@ -1,6 +1,9 @@
 package entities
 
-import "github.com/nbah1990/goncanode/types"
+import (
+	"github.com/nbah1990/goncanode/types"
+	"log/slog"
+)
 
 type Response struct {
 	Result  ResponseResult `json:"result"`
@@ -24,4 +27,24 @@ type SignParams struct {
 	P12      string `json:"p12"`
 	Password string `json:"password"`
 	Xml      string `json:"xml"`
+	Alias    string `json:"alias,omitempty"`
+}
+
+// LogValue keeps the key and its password out of logs.
+func (p SignParams) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String(`p12`, `[REDACTED]`),
+		slog.String(`password`, `[REDACTED]`),
+		slog.Int(`xml_bytes`, len(p.Xml)),
+		slog.String(`alias`, p.Alias),
+	)
+}
+
+func (r SignRequest) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String(`version`, r.Version),
+		slog.String(`method`, r.Method),
+		slog.String(`tspHashAlgorithm`, string(r.TspHashAlgorithm)),
+		slog.Any(`params`, r.Params),
+	)
 }
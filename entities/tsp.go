@@ -0,0 +1,9 @@
+package entities
+
+import "github.com/nbah1990/goncanode/types"
+
+// Tsp makes NCANode v3 timestamp every CMS signature with the TSA configured on the NCANode side.
+// There is deliberately no TSA URL: the v3 sign requests have no field for it, NCANode's own configuration sets it.
+type Tsp struct {
+	Policy types.TsaPolicy
+}
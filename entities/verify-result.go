@@ -0,0 +1,6 @@
+package entities
+
+type VerifyResult struct {
+	Valid   bool          `json:"valid"`
+	Signers []Certificate `json:"signers"`
+}
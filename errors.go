@@ -0,0 +1,215 @@
+package goncanode
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/nbah1990/goncanode/api"
+	"github.com/nbah1990/goncanode/entities"
+	"github.com/nbah1990/goncanode/types"
+	"strings"
+)
+
+var (
+	ErrUnknownVersion     = errors.New("unknown version")
+	ErrInvalidKey         = errors.New("invalid key or password")
+	ErrExpiredCertificate = errors.New("certificate expired")
+	ErrRevokedCertificate = errors.New("certificate revoked")
+	ErrUnsupported        = errors.New("not supported by this NCANode version")
+)
+
+// TransportError means the request didn't get a usable answer from NCANode: network failure, timeout, cancellation.
+type TransportError struct {
+	Op  string
+	Err error
+}
+
+func (e *TransportError) Error() string {
+	return fmt.Sprintf(`%s: http request error: %s`, e.Op, e.Err)
+}
+
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeError means NCANode answered with something goncanode can't read: not JSON or a malformed base64 field.
+type DecodeError struct {
+	Op   string
+	What string
+	Err  error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf(`%s: can't decode %s: %s`, e.Op, e.What, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// InputError means the data passed by the caller couldn't be read, no request was sent to NCANode.
+type InputError struct {
+	Op  string
+	Err error
+}
+
+func (e *InputError) Error() string {
+	return fmt.Sprintf(`%s: can't read data: %s`, e.Op, e.Err)
+}
+
+func (e *InputError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	errNilReader         = errors.New(`data reader is nil`)
+	errEmptyCertificates = errors.New(`empty certificate list`)
+)
+
+// NodeError is an unsuccessful status reported by NCANode itself.
+// It unwraps to ErrInvalidKey, ErrExpiredCertificate or ErrRevokedCertificate when the message allows to tell.
+type NodeError struct {
+	Op      string
+	Status  int
+	Message string
+}
+
+func (e *NodeError) Error() string {
+	return fmt.Sprintf(`%s: http error: %s, status: %d`, e.Op, e.Message, e.Status)
+}
+
+func (e *NodeError) Unwrap() error {
+	m := strings.ToLower(e.Message)
+
+	for _, c := range nodeErrorKinds {
+		for _, p := range c.patterns {
+			if strings.Contains(m, p) {
+				return c.err
+			}
+		}
+	}
+
+	return nil
+}
+
+var nodeErrorKinds = []struct {
+	err      error
+	patterns []string
+}{
+	{ErrRevokedCertificate, []string{`revoked`, `отозван`}},
+	{ErrExpiredCertificate, []string{`expired`, `истек`, `не действителен`}},
+	{ErrInvalidKey, []string{`password`, `пароль`, `keystore`, `key store`, `cannot load key`, `invalid key`, `pkcs12`}},
+}
+
+// requestError keeps NCANode's own status and message when it answered a non-2xx status with its JSON error body.
+func requestError(op string, err error, body []byte) error {
+	var httpErr *api.HTTPError
+	if !errors.As(err, &httpErr) {
+		return &TransportError{Op: op, Err: err}
+	}
+
+	var s struct {
+		Status  int    `json:"status"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &s) != nil || s.Message == `` {
+		return &TransportError{Op: op, Err: err}
+	}
+
+	if s.Status == 0 {
+		s.Status = httpErr.StatusCode
+	}
+
+	return &NodeError{Op: op, Status: s.Status, Message: s.Message}
+}
+
+// UnsupportedError is returned before any request is made when an option or mode can't be honored
+// by the NCANode version, it matches ErrUnsupported.
+type UnsupportedError struct {
+	Op      string
+	Feature string
+	Version string
+}
+
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf(`%s: %s is not supported by NCANode %s`, e.Op, e.Feature, e.Version)
+}
+
+func (e *UnsupportedError) Is(target error) bool {
+	return target == ErrUnsupported
+}
+
+// HashAlgorithmError is returned before any request is made for a hash algorithm that is unknown
+// or that the NCANode version can't honor for the call. It matches types.ErrUnknownHashAlgorithm or ErrUnsupported.
+type HashAlgorithmError struct {
+	Op        string
+	Algorithm types.HashAlgorithm
+	Version   types.Version
+}
+
+func (e *HashAlgorithmError) Error() string {
+	if !e.Algorithm.Valid() {
+		return fmt.Sprintf(`%s: unknown hash algorithm %q`, e.Op, e.Algorithm)
+	}
+
+	return fmt.Sprintf(`%s: hash algorithm %s is not supported by NCANode %s`, e.Op, e.Algorithm, versionName(e.Version))
+}
+
+func (e *HashAlgorithmError) Is(target error) bool {
+	if !e.Algorithm.Valid() {
+		return target == types.ErrUnknownHashAlgorithm
+	}
+
+	return target == ErrUnsupported
+}
+
+// versionName formats v the way UnsupportedError names versions, e.g. v3 for 3.0.
+func versionName(v types.Version) string {
+	return `v` + strings.TrimSuffix(string(v), `.0`)
+}
+
+// KeyError means the configured KeyProvider couldn't supply the key, no request was sent to NCANode.
+type KeyError struct {
+	Op  string
+	Err error
+}
+
+func (e *KeyError) Error() string {
+	return fmt.Sprintf(`%s: can't get key: %s`, e.Op, e.Err)
+}
+
+func (e *KeyError) Unwrap() error {
+	return e.Err
+}
+
+// resolveKey prefers a key set per call over the provider and the handler's key.
+// The alias set on ctx wins over the one supplied with the key, which wins over the handler's one.
+// A key set per call never gets the handler's alias, it belongs to another container.
+func resolveKey(ctx context.Context, op string, p entities.KeyProvider, p12base64 string, password string, alias string) (entities.Key, error) {
+	k := entities.Key{P12base64: p12base64, Password: password}
+
+	if o, ok := keyOverride(ctx); ok {
+		k = o
+	} else {
+		if p != nil {
+			var err error
+			k, err = p.Key(ctx)
+			if err != nil {
+				return k, &KeyError{Op: op, Err: err}
+			}
+		}
+		if k.Alias == `` {
+			k.Alias = alias
+		}
+	}
+	if a, ok := keyAlias(ctx); ok {
+		k.Alias = a
+	}
+
+	return k, nil
+}
+
+func encodeError(op string, err error) error {
+	return fmt.Errorf(`%s: can't encode request json: %w`, op, err)
+}
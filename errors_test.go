@@ -0,0 +1,55 @@
+package goncanode
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNodeError_Unwrap(t *testing.T) {
+	tests := []struct {
+		message string
+		want    error
+	}{
+		{"Invalid password", ErrInvalidKey},
+		{"Неверный пароль", ErrInvalidKey},
+		{"Cannot load key store", ErrInvalidKey},
+		{"Certificate has expired", ErrExpiredCertificate},
+		{"Срок действия сертификата истек", ErrExpiredCertificate},
+		{"Certificate revoked by OCSP", ErrRevokedCertificate},
+		{"Сертификат отозван", ErrRevokedCertificate},
+		{"Bad Request", nil},
+	}
+
+	for _, tt := range tests {
+		err := &NodeError{Op: "SignXml", Status: 400, Message: tt.message}
+		if got := errors.Unwrap(err); got != tt.want {
+			t.Errorf("Message: %s, expected: %v, got: %v", tt.message, tt.want, got)
+		}
+		if tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("Message: %s, expected errors.Is to match %v", tt.message, tt.want)
+		}
+	}
+}
+
+func TestErrorMessages(t *testing.T) {
+	cause := errors.New("cause")
+
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{&TransportError{Op: "SignXml", Err: cause}, "SignXml: http request error: cause"},
+		{&DecodeError{Op: "SignXml", What: "http response json", Err: cause}, "SignXml: can't decode http response json: cause"},
+		{&NodeError{Op: "SignXml", Status: 500, Message: "failure"}, "SignXml: http error: failure, status: 500"},
+		{&InputError{Op: "SignCms", Err: cause}, "SignCms: can't read data: cause"},
+	}
+
+	for _, tt := range tests {
+		if tt.err.Error() != tt.want {
+			t.Errorf("Expected: %s, got: %s", tt.want, tt.err.Error())
+		}
+		if _, ok := tt.err.(*NodeError); !ok && !errors.Is(tt.err, cause) {
+			t.Errorf("Expected %T to wrap its cause", tt.err)
+		}
+	}
+}
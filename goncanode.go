@@ -2,41 +2,168 @@ package goncanode
 
 import (
 	"context"
-	"errors"
 	"github.com/nbah1990/goncanode/api"
 	"github.com/nbah1990/goncanode/entities"
 	"github.com/nbah1990/goncanode/types"
+	"io"
+	"net/http"
 )
 
 type Handler interface {
-	SignWithSecurityHeader(ctx context.Context, xml string, hashAlgorithm types.HashAlgorithm) (result entities.Response, err error)
+	SignWithSecurityHeader(ctx context.Context, xml string, hashAlgorithm types.HashAlgorithm, opts ...SignOption) (result entities.Response, err error)
+	VerifyXml(ctx context.Context, xml string, checks ...types.RevocationCheck) (result entities.VerifyResult, err error)
+	VerifyWithSecurityHeader(ctx context.Context, xml string, checks ...types.RevocationCheck) (result entities.VerifyResult, err error)
+	SignCms(ctx context.Context, data io.Reader, detached bool, opts ...SignOption) (result entities.CmsResult, err error)
+	SignCmsBytes(ctx context.Context, data []byte, detached bool, opts ...SignOption) (result entities.CmsResult, err error)
+	VerifyCms(ctx context.Context, cms []byte, data []byte, checks ...types.RevocationCheck) (result entities.CmsVerifyResult, err error)
+	ExtractCms(ctx context.Context, cms []byte) (data []byte, err error)
+	X509Info(ctx context.Context, cert []byte, checks ...types.RevocationCheck) (result entities.Certificate, err error)
+	Pkcs12Info(ctx context.Context, checks ...types.RevocationCheck) (result entities.Certificate, err error)
+	Pkcs12Aliases(ctx context.Context) (result []entities.KeyAlias, err error)
 }
 
+// New validates o, applies DefaultVersion and DefaultTimeout and returns the handler for o.Version.
+// Validation problems are reported together as *OptionsError.
+func New(o entities.Options) (Handler, error) {
+	err := ValidateOptions(o)
+	if err != nil {
+		return nil, err
+	}
+
+	o, err = loadKey(o)
+	if err != nil {
+		return nil, err
+	}
+
+	return newHandler(withDefaults(o))
+}
+
+// Create is kept for compatibility: it does no validation and panics on an unknown version
+// or a key loaded from P12File or P12Reader that doesn't open, prefer New.
 func Create(o entities.Options) Handler {
 	if o.Version == nil {
-		v := types.NCAnodeV10
+		v := DefaultVersion
 		o.Version = &v
 	}
 
-	a := api.Client{
-		BaseUrl: o.ServiceUrl,
+	o, err := loadKey(o)
+	if err != nil {
+		panic(err)
+	}
+
+	h, err := newHandler(o)
+	if err != nil {
+		panic(err)
+	}
+
+	return h
+}
+
+func newHandler(o entities.Options) (Handler, error) {
+	a, err := newApiClient(o)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.Tsp != nil && *o.Version != types.NCAnodeV30 {
+		return nil, &UnsupportedError{Op: `New`, Feature: `Tsp`, Version: versionName(*o.Version)}
 	}
 
 	if *o.Version == types.NCAnodeV10 {
 		return &NCANodeV1Handler{
 			P12pass:   o.P12pass,
 			P12base64: o.P12base64,
+			Keys:      o.Keys,
+			KeyAlias:  o.KeyAlias,
 			Timeout:   o.Timeout,
-			Api:       &a,
-		}
+			Logger:    o.Logger,
+			Tracer:    o.Tracer,
+			Metrics:   o.Metrics,
+			Api:       a,
+		}, nil
+	} else if *o.Version == types.NCAnodeV20 {
+		return &NCANodeV2Handler{
+			P12pass:   o.P12pass,
+			P12base64: o.P12base64,
+			Keys:      o.Keys,
+			KeyAlias:  o.KeyAlias,
+			Timeout:   o.Timeout,
+			Logger:    o.Logger,
+			Tracer:    o.Tracer,
+			Metrics:   o.Metrics,
+			Api:       a,
+		}, nil
 	} else if *o.Version == types.NCAnodeV30 {
 		return &NCANodeV3Handler{
 			P12pass:   o.P12pass,
 			P12base64: o.P12base64,
+			Keys:      o.Keys,
+			KeyAlias:  o.KeyAlias,
 			Timeout:   o.Timeout,
-			Api:       &a,
+			Tsp:       o.Tsp,
+			Logger:    o.Logger,
+			Tracer:    o.Tracer,
+			Metrics:   o.Metrics,
+			Api:       a,
+		}, nil
+	}
+
+	return nil, ErrUnknownVersion
+}
+
+func newApiClient(o entities.Options) (api.IClient, error) {
+	hc, err := newHTTPClient(o)
+	if err != nil {
+		return nil, err
+	}
+
+	var a api.IClient = &api.Client{
+		BaseUrl:    o.ServiceUrl,
+		HTTPClient: hc,
+		Decorators: o.Decorators,
+		Logger:     o.Logger,
+		Tracer:     o.Tracer,
+		Metrics:    o.Metrics,
+	}
+
+	if len(o.ServiceUrls) > 0 {
+		m := api.NewMultiClient(hc, o.ServiceUrls...)
+		m.Strategy = o.Balancing
+		m.Use(o.Decorators...)
+		m.SetLogger(o.Logger)
+		m.SetTracer(o.Tracer)
+		m.SetMetrics(o.Metrics)
+		a = m
+	}
+
+	if o.CircuitBreaker != nil {
+		a = o.CircuitBreaker.Wrap(a)
+	}
+
+	if o.Retry != nil {
+		a = &api.RetryClient{Client: a, Policy: *o.Retry}
+	}
+
+	return a, nil
+}
+
+func newHTTPClient(o entities.Options) (*http.Client, error) {
+	if o.HTTPClient != nil {
+		return o.HTTPClient, nil
+	}
+
+	c := api.DefaultTransportConfig()
+	if o.Transport != nil {
+		c = *o.Transport
+	}
+
+	if o.TLS != nil {
+		tc, err := o.TLS.Build()
+		if err != nil {
+			return nil, err
 		}
+		c.TLSClientConfig = tc
 	}
 
-	panic(errors.New("unknown version"))
+	return api.NewHTTPClient(c), nil
 }
@@ -1,7 +1,15 @@
 package goncanode
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/nbah1990/goncanode/api"
 	"github.com/nbah1990/goncanode/types"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -64,6 +72,34 @@ func TestCreate(t *testing.T) {
 		}
 	})
 
+	t.Run("VersionV2", func(t *testing.T) {
+		version := types.NCAnodeV20
+		options := entities.Options{
+			ServiceUrl: "https://example.com",
+			P12base64:  "base64string",
+			P12pass:    "password",
+			Timeout:    5 * time.Second,
+			Version:    &version,
+		}
+
+		handler := Create(options)
+
+		if _, ok := handler.(*NCANodeV2Handler); !ok {
+			t.Errorf("Expected handler type *NCANodeV2Handler, got %T", handler)
+		}
+
+		v2Handler := handler.(*NCANodeV2Handler)
+		if v2Handler.P12base64 != options.P12base64 {
+			t.Errorf("Expected P12base64 %s, got %s", options.P12base64, v2Handler.P12base64)
+		}
+		if v2Handler.P12pass != options.P12pass {
+			t.Errorf("Expected P12pass %s, got %s", options.P12pass, v2Handler.P12pass)
+		}
+		if v2Handler.Timeout != options.Timeout {
+			t.Errorf("Expected Timeout %v, got %v", options.Timeout, v2Handler.Timeout)
+		}
+	})
+
 	t.Run("VersionV3", func(t *testing.T) {
 		version := types.NCAnodeV30
 		options := entities.Options{
@@ -120,3 +156,228 @@ func TestCreate(t *testing.T) {
 		_ = Create(options)
 	})
 }
+
+func TestNew(t *testing.T) {
+	t.Run("Defaults", func(t *testing.T) {
+		handler, err := New(entities.Options{
+			ServiceUrl: "https://example.com",
+			P12base64:  "base64string",
+			P12pass:    "password",
+		})
+		if err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+
+		v1Handler, ok := handler.(*NCANodeV1Handler)
+		if !ok {
+			t.Fatalf("Expected handler type *NCANodeV1Handler, got %T", handler)
+		}
+		if v1Handler.Timeout != DefaultTimeout {
+			t.Errorf("Expected Timeout %v, got %v", DefaultTimeout, v1Handler.Timeout)
+		}
+	})
+
+	t.Run("VersionV3", func(t *testing.T) {
+		version := types.NCAnodeV30
+		handler, err := New(entities.Options{
+			ServiceUrl: "https://example.com",
+			P12base64:  "base64string",
+			P12pass:    "password",
+			Timeout:    5 * time.Second,
+			Version:    &version,
+		})
+		if err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+
+		v3Handler, ok := handler.(*NCANodeV3Handler)
+		if !ok {
+			t.Fatalf("Expected handler type *NCANodeV3Handler, got %T", handler)
+		}
+		if v3Handler.Timeout != 5*time.Second {
+			t.Errorf("Expected Timeout %v, got %v", 5*time.Second, v3Handler.Timeout)
+		}
+	})
+
+	t.Run("InvalidOptions", func(t *testing.T) {
+		unknownVersion := types.Version("unknown")
+		handler, err := New(entities.Options{
+			P12base64: "not base64!",
+			Timeout:   -time.Second,
+			Version:   &unknownVersion,
+		})
+		if handler != nil {
+			t.Errorf("Expected nil handler, got %T", handler)
+		}
+
+		var optionsErr *OptionsError
+		if !errors.As(err, &optionsErr) {
+			t.Fatalf("Expected *OptionsError, got: %v", err)
+		}
+		if len(optionsErr.Problems) != 4 {
+			t.Errorf("Expected 4 problems, got: %v", optionsErr)
+		}
+	})
+}
+
+func TestNew_Retry(t *testing.T) {
+	policy := api.DefaultRetryPolicy()
+	handler, err := New(entities.Options{
+		ServiceUrl: "https://example.com",
+		P12base64:  "base64string",
+		Retry:      &policy,
+	})
+	if err != nil {
+		t.Fatalf("Expected no errors, got: %v", err)
+	}
+
+	rc, ok := handler.(*NCANodeV1Handler).Api.(*api.RetryClient)
+	if !ok {
+		t.Fatalf("Expected *api.RetryClient, got %T", handler.(*NCANodeV1Handler).Api)
+	}
+	if rc.Policy.MaxAttempts != policy.MaxAttempts {
+		t.Errorf("Expected MaxAttempts %d, got %d", policy.MaxAttempts, rc.Policy.MaxAttempts)
+	}
+}
+
+func TestNew_ServiceUrls(t *testing.T) {
+	handler, err := New(entities.Options{
+		ServiceUrls: []string{"http://a:14579", "http://b:14579"},
+		Balancing:   api.LeastInFlight,
+		P12base64:   "base64string",
+	})
+	if err != nil {
+		t.Fatalf("Expected no errors, got: %v", err)
+	}
+
+	mc, ok := handler.(*NCANodeV1Handler).Api.(*api.MultiClient)
+	if !ok {
+		t.Fatalf("Expected *api.MultiClient, got %T", handler.(*NCANodeV1Handler).Api)
+	}
+	if mc.Strategy != api.LeastInFlight || len(mc.Endpoints()) != 2 {
+		t.Errorf("Unexpected multi client: %+v", mc.Endpoints())
+	}
+}
+
+func TestNew_Decorators(t *testing.T) {
+	d := api.BasicAuth("user", "secret")
+
+	handler, err := New(entities.Options{
+		ServiceUrl: "https://example.com",
+		P12base64:  "base64string",
+		Decorators: []api.RequestDecorator{d},
+	})
+	if err != nil {
+		t.Fatalf("Expected no errors, got: %v", err)
+	}
+
+	if c := handler.(*NCANodeV1Handler).Api.(*api.Client); len(c.Decorators) != 1 {
+		t.Errorf("Expected decorators to be passed to the client, got %v", c.Decorators)
+	}
+}
+
+func TestNew_CircuitBreaker(t *testing.T) {
+	breaker := api.NewCircuitBreaker(nil)
+	handler, err := New(entities.Options{
+		ServiceUrl:     "https://example.com",
+		P12base64:      "base64string",
+		CircuitBreaker: breaker,
+	})
+	if err != nil {
+		t.Fatalf("Expected no errors, got: %v", err)
+	}
+
+	if handler.(*NCANodeV1Handler).Api == breaker {
+		t.Errorf("Expected the breaker to be wrapped, not used as handler client")
+	}
+	if breaker.Client != nil {
+		t.Errorf("Expected breaker client to be left alone, got %T", breaker.Client)
+	}
+}
+
+// Run with -race: a second New must neither rewire nor race with the handlers already sharing the breaker.
+func TestNew_SharedCircuitBreaker(t *testing.T) {
+	var hitsA, hitsB atomic.Int64
+	a := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { hitsA.Add(1) }))
+	defer a.Close()
+	b := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { hitsB.Add(1) }))
+	defer b.Close()
+
+	breaker := api.NewCircuitBreaker(nil)
+	first, err := New(entities.Options{ServiceUrl: a.URL, P12base64: "base64string", CircuitBreaker: breaker})
+	if err != nil {
+		t.Fatalf("Expected no errors, got: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				if _, err := first.(*NCANodeV1Handler).Api.Request(context.Background(), http.MethodPost, "/", &bytes.Buffer{}); err != nil {
+					t.Errorf("Expected no errors, got: %v", err)
+				}
+			}
+		}()
+	}
+
+	second, err := New(entities.Options{ServiceUrl: b.URL, P12base64: "base64string", CircuitBreaker: breaker})
+	if err != nil {
+		t.Fatalf("Expected no errors, got: %v", err)
+	}
+	if _, err := second.(*NCANodeV1Handler).Api.Request(context.Background(), http.MethodPost, "/", &bytes.Buffer{}); err != nil {
+		t.Errorf("Expected no errors, got: %v", err)
+	}
+	wg.Wait()
+
+	if hitsA.Load() != 40 || hitsB.Load() != 1 {
+		t.Errorf("Expected 40 requests to the first and 1 to the second node, got %d and %d", hitsA.Load(), hitsB.Load())
+	}
+}
+
+func TestNew_HTTPClient(t *testing.T) {
+	t.Run("Injected", func(t *testing.T) {
+		hc := &http.Client{}
+		handler, err := New(entities.Options{
+			ServiceUrl: "https://example.com",
+			P12base64:  "base64string",
+			HTTPClient: hc,
+		})
+		if err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+
+		if c := handler.(*NCANodeV1Handler).Api.(*api.Client); c.HTTPClient != hc {
+			t.Errorf("Expected injected http client, got %v", c.HTTPClient)
+		}
+	})
+
+	t.Run("Transport", func(t *testing.T) {
+		tc := api.DefaultTransportConfig()
+		tc.MaxIdleConnsPerHost = 7
+		handler, err := New(entities.Options{
+			ServiceUrl: "https://example.com",
+			P12base64:  "base64string",
+			Transport:  &tc,
+		})
+		if err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+
+		c := handler.(*NCANodeV1Handler).Api.(*api.Client)
+		tr, ok := c.HTTPClient.Transport.(*http.Transport)
+		if !ok || tr.MaxIdleConnsPerHost != 7 {
+			t.Errorf("Expected configured transport, got %+v", c.HTTPClient.Transport)
+		}
+	})
+
+	t.Run("Create", func(t *testing.T) {
+		handler := Create(entities.Options{ServiceUrl: "https://example.com"})
+
+		c := handler.(*NCANodeV1Handler).Api.(*api.Client)
+		if c.HTTPClient == nil || c.HTTPClient.Transport == nil {
+			t.Errorf("Expected Create to build a configured http client, got %+v", c.HTTPClient)
+		}
+	})
+}
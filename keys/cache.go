@@ -0,0 +1,48 @@
+package keys
+
+import (
+	"context"
+	"github.com/nbah1990/goncanode/entities"
+	"sync"
+	"time"
+)
+
+// Cache keeps the key of a slow provider, e.g. a secret manager, for TTL. Failed fetches aren't cached.
+type Cache struct {
+	Provider entities.KeyProvider
+	TTL      time.Duration
+
+	mu        sync.Mutex
+	key       entities.Key
+	fetchedAt time.Time
+	cached    bool
+}
+
+func Cached(p entities.KeyProvider, ttl time.Duration) *Cache {
+	return &Cache{Provider: p, TTL: ttl}
+}
+
+func (c *Cache) Key(ctx context.Context) (entities.Key, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cached && time.Since(c.fetchedAt) < c.TTL {
+		return c.key, nil
+	}
+
+	k, err := c.Provider.Key(ctx)
+	if err != nil {
+		return k, err
+	}
+
+	c.key, c.fetchedAt, c.cached = k, time.Now(), true
+
+	return k, nil
+}
+
+// Invalidate drops the cached key, the next call fetches it again.
+func (c *Cache) Invalidate() {
+	c.mu.Lock()
+	c.cached = false
+	c.mu.Unlock()
+}
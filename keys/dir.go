@@ -0,0 +1,133 @@
+package keys
+
+import (
+	"context"
+	"fmt"
+	"github.com/nbah1990/goncanode/entities"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultKeyFile      = `key.p12`
+	DefaultPasswordFile = `password`
+	DefaultDirInterval  = 10 * time.Second
+)
+
+// Dir serves the key from a directory such as a mounted Kubernetes secret and reloads it once the files change.
+// Modification times are checked at most once per Interval, the key is reused in between.
+type Dir struct {
+	Path         string
+	KeyFile      string
+	PasswordFile string
+	Interval     time.Duration
+
+	mu      sync.Mutex
+	key     entities.Key
+	modTime time.Time
+	checked time.Time
+	loaded  bool
+}
+
+func NewDir(path string) *Dir {
+	return &Dir{
+		Path:         path,
+		KeyFile:      DefaultKeyFile,
+		PasswordFile: DefaultPasswordFile,
+		Interval:     DefaultDirInterval,
+	}
+}
+
+func (d *Dir) Key(context.Context) (entities.Key, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	if d.loaded && now.Sub(d.checked) < d.interval() {
+		return d.key, nil
+	}
+
+	modTime, err := d.lastModified()
+	if err != nil {
+		return entities.Key{}, err
+	}
+
+	d.checked = now
+	if d.loaded && modTime.Equal(d.modTime) {
+		return d.key, nil
+	}
+
+	k, err := d.load()
+	if err != nil {
+		return entities.Key{}, err
+	}
+
+	d.key, d.modTime, d.loaded = k, modTime, true
+
+	return k, nil
+}
+
+func (d *Dir) lastModified() (t time.Time, err error) {
+	for _, f := range []string{d.keyFile(), d.passwordFile()} {
+		fi, err := os.Stat(filepath.Join(d.Path, f))
+		if os.IsNotExist(err) && f == d.passwordFile() {
+			continue
+		}
+		if err != nil {
+			return t, fmt.Errorf(`keys: %w`, err)
+		}
+		if fi.ModTime().After(t) {
+			t = fi.ModTime()
+		}
+	}
+
+	return t, nil
+}
+
+// load reads the key file and the optional password file, a trailing newline of the password is dropped.
+func (d *Dir) load() (entities.Key, error) {
+	path := filepath.Join(d.Path, d.keyFile())
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return entities.Key{}, fmt.Errorf(`keys: %w`, err)
+	}
+
+	p12, err := encodeP12(b)
+	if err != nil {
+		return entities.Key{}, fmt.Errorf(`keys: %s: %w`, path, err)
+	}
+
+	password, err := os.ReadFile(filepath.Join(d.Path, d.passwordFile()))
+	if err != nil && !os.IsNotExist(err) {
+		return entities.Key{}, fmt.Errorf(`keys: %w`, err)
+	}
+
+	return entities.Key{P12base64: p12, Password: strings.TrimRight(string(password), "\r\n")}, nil
+}
+
+func (d *Dir) keyFile() string {
+	if d.KeyFile == `` {
+		return DefaultKeyFile
+	}
+
+	return d.KeyFile
+}
+
+func (d *Dir) passwordFile() string {
+	if d.PasswordFile == `` {
+		return DefaultPasswordFile
+	}
+
+	return d.PasswordFile
+}
+
+func (d *Dir) interval() time.Duration {
+	if d.Interval <= 0 {
+		return DefaultDirInterval
+	}
+
+	return d.Interval
+}
@@ -0,0 +1,92 @@
+// Package keys provides entities.KeyProvider implementations for keys kept in memory, files, environment variables,
+// rotated secret directories and external secret managers.
+package keys
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/nbah1990/goncanode/entities"
+	"os"
+	"strings"
+)
+
+type static entities.Key
+
+// Static always returns the same key, it is what Options.P12base64 and Options.P12pass amount to.
+func Static(p12base64 string, password string) entities.KeyProvider {
+	return static{P12base64: p12base64, Password: password}
+}
+
+func (s static) Key(context.Context) (entities.Key, error) {
+	return entities.Key(s), nil
+}
+
+// Func adapts a callback, e.g. a secret manager client, to entities.KeyProvider.
+type Func func(ctx context.Context) (entities.Key, error)
+
+func (f Func) Key(ctx context.Context) (entities.Key, error) {
+	return f(ctx)
+}
+
+type env struct {
+	p12Var      string
+	passwordVar string
+}
+
+// Env reads the base64 encoded container and its password from environment variables on every call.
+func Env(p12Var string, passwordVar string) entities.KeyProvider {
+	return env{p12Var: p12Var, passwordVar: passwordVar}
+}
+
+func (e env) Key(context.Context) (entities.Key, error) {
+	p12, ok := os.LookupEnv(e.p12Var)
+	if !ok || strings.TrimSpace(p12) == `` {
+		return entities.Key{}, fmt.Errorf(`keys: environment variable %s is not set`, e.p12Var)
+	}
+
+	return entities.Key{P12base64: p12, Password: os.Getenv(e.passwordVar)}, nil
+}
+
+type file struct {
+	path     string
+	password string
+}
+
+// File reads the container from path on every call, both binary .p12 files and base64 text are accepted.
+func File(path string, password string) entities.KeyProvider {
+	return file{path: path, password: password}
+}
+
+func (f file) Key(context.Context) (entities.Key, error) {
+	b, err := os.ReadFile(f.path)
+	if err != nil {
+		return entities.Key{}, fmt.Errorf(`keys: %w`, err)
+	}
+
+	p12, err := encodeP12(b)
+	if err != nil {
+		return entities.Key{}, fmt.Errorf(`keys: %s: %w`, f.path, err)
+	}
+
+	return entities.Key{P12base64: p12, Password: f.password}, nil
+}
+
+// encodeP12 base64 encodes a DER container, which always starts with a SEQUENCE tag, and passes base64 text through.
+func encodeP12(b []byte) (string, error) {
+	if len(b) == 0 {
+		return ``, errors.New(`empty key file`)
+	}
+
+	if b[0] == 0x30 {
+		return base64.StdEncoding.EncodeToString(b), nil
+	}
+
+	s := strings.Join(strings.Fields(string(b)), ``)
+	if _, err := base64.StdEncoding.DecodeString(s); err != nil {
+		return ``, fmt.Errorf(`neither a p12 file nor base64: %w`, err)
+	}
+
+	return s, nil
+}
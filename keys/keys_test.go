@@ -0,0 +1,125 @@
+package keys
+
+import (
+	"context"
+	"errors"
+	"github.com/nbah1990/goncanode/entities"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStatic(t *testing.T) {
+	k, err := Static("MIIBAg==", "password").Key(context.Background())
+	if err != nil || k.P12base64 != "MIIBAg==" || k.Password != "password" {
+		t.Errorf("Unexpected key %+v, err: %v", k, err)
+	}
+}
+
+func TestFunc(t *testing.T) {
+	fetchErr := errors.New("secret manager unavailable")
+	_, err := Func(func(ctx context.Context) (entities.Key, error) { return entities.Key{}, fetchErr }).Key(context.Background())
+	if !errors.Is(err, fetchErr) {
+		t.Errorf("Expected callback error, got: %v", err)
+	}
+}
+
+func TestEnv(t *testing.T) {
+	t.Setenv("NCANODE_P12", "MIIBAg==")
+	t.Setenv("NCANODE_P12_PASSWORD", "password")
+
+	k, err := Env("NCANODE_P12", "NCANODE_P12_PASSWORD").Key(context.Background())
+	if err != nil || k.P12base64 != "MIIBAg==" || k.Password != "password" {
+		t.Errorf("Unexpected key %+v, err: %v", k, err)
+	}
+
+	if _, err := Env("NCANODE_MISSING", "NCANODE_P12_PASSWORD").Key(context.Background()); err == nil {
+		t.Errorf("Expected error for unset variable")
+	}
+}
+
+func TestFile(t *testing.T) {
+	dir := t.TempDir()
+	der := filepath.Join(dir, "key.p12")
+	text := filepath.Join(dir, "key.b64")
+	invalid := filepath.Join(dir, "key.txt")
+	_ = os.WriteFile(der, []byte{0x30, 0x82, 0x01, 0x02}, 0o600)
+	_ = os.WriteFile(text, []byte("MIIB\nAg==\n"), 0o600)
+	_ = os.WriteFile(invalid, []byte("not base64!"), 0o600)
+
+	for _, path := range []string{der, text} {
+		k, err := File(path, "password").Key(context.Background())
+		if err != nil || k.P12base64 != "MIIBAg==" || k.Password != "password" {
+			t.Errorf("%s: unexpected key %+v, err: %v", path, k, err)
+		}
+	}
+
+	for _, path := range []string{invalid, filepath.Join(dir, "missing.p12")} {
+		if _, err := File(path, "password").Key(context.Background()); err == nil {
+			t.Errorf("%s: expected error, got nil", path)
+		}
+	}
+}
+
+func TestDir(t *testing.T) {
+	dir := t.TempDir()
+	write := func(p12 []byte, password string, mod time.Time) {
+		_ = os.WriteFile(filepath.Join(dir, DefaultKeyFile), p12, 0o600)
+		_ = os.WriteFile(filepath.Join(dir, DefaultPasswordFile), []byte(password), 0o600)
+		_ = os.Chtimes(filepath.Join(dir, DefaultKeyFile), mod, mod)
+		_ = os.Chtimes(filepath.Join(dir, DefaultPasswordFile), mod, mod)
+	}
+
+	write([]byte{0x30, 0x01}, "first\n", time.Now().Add(-time.Hour))
+
+	d := NewDir(dir)
+	d.Interval = time.Nanosecond
+
+	k, err := d.Key(context.Background())
+	if err != nil || k.P12base64 != "MAE=" || k.Password != "first" {
+		t.Fatalf("Unexpected key %+v, err: %v", k, err)
+	}
+
+	write([]byte{0x30, 0x02}, "second", time.Now())
+
+	k, err = d.Key(context.Background())
+	if err != nil || k.P12base64 != "MAI=" || k.Password != "second" {
+		t.Errorf("Expected rotated key, got %+v, err: %v", k, err)
+	}
+
+	d.Interval = time.Hour
+	write([]byte{0x30, 0x03}, "third", time.Now().Add(time.Minute))
+	if k, _ = d.Key(context.Background()); k.Password != "second" {
+		t.Errorf("Expected key to be reused within Interval, got %+v", k)
+	}
+}
+
+func TestDir_Missing(t *testing.T) {
+	if _, err := NewDir(t.TempDir()).Key(context.Background()); err == nil {
+		t.Errorf("Expected error for missing key file")
+	}
+}
+
+func TestCache(t *testing.T) {
+	fetches := 0
+	c := Cached(Func(func(ctx context.Context) (entities.Key, error) {
+		fetches++
+		return entities.Key{P12base64: "MIIBAg==", Password: "password"}, nil
+	}), time.Hour)
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.Key(context.Background()); err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+	}
+	if fetches != 1 {
+		t.Errorf("Expected 1 fetch within TTL, got %d", fetches)
+	}
+
+	c.Invalidate()
+	_, _ = c.Key(context.Background())
+	if fetches != 2 {
+		t.Errorf("Expected fetch after Invalidate, got %d", fetches)
+	}
+}
@@ -0,0 +1,102 @@
+package goncanode
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/nbah1990/goncanode/types"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// MaxLoggedValueLength truncates documents, certificates and other long strings of debug level payloads.
+var MaxLoggedValueLength = 256
+
+const redacted = `[REDACTED]`
+
+// secretFields are the JSON fields carrying key material or passwords in requests of every NCANode version.
+var secretFields = map[string]bool{
+	`p12`:      true,
+	`key`:      true,
+	`password`: true,
+}
+
+// logCall reports one handler operation, request and response bodies are only logged at debug level and redacted.
+func logCall(ctx context.Context, l *slog.Logger, v types.Version, op string, endpoint string, start time.Time, status int, req []byte, resp []byte, err error) {
+	if l == nil {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String(`version`, string(v)),
+		slog.String(`op`, op),
+		slog.String(`endpoint`, endpoint),
+		slog.Duration(`duration`, time.Since(start)),
+		slog.Int(`status`, callStatus(status, err)),
+		slog.Int(`request_bytes`, len(req)),
+		slog.Int(`response_bytes`, len(resp)),
+	}
+
+	level := slog.LevelInfo
+	if err != nil {
+		level = slog.LevelError
+		attrs = append(attrs, slog.String(`error`, err.Error()), slog.String(`error_class`, ErrorClass(err)))
+	}
+
+	l.LogAttrs(ctx, level, `ncanode call`, attrs...)
+
+	if l.Enabled(ctx, slog.LevelDebug) {
+		l.LogAttrs(ctx, slog.LevelDebug, `ncanode payload`,
+			slog.String(`op`, op),
+			slog.String(`request`, redactPayload(req)),
+			slog.String(`response`, redactPayload(resp)),
+		)
+	}
+}
+
+// redactPayload hides secretFields at any depth of a JSON body and truncates long values, non-JSON bodies are only truncated.
+func redactPayload(b []byte) string {
+	var v interface{}
+	if json.Unmarshal(b, &v) != nil {
+		return truncateValue(string(b))
+	}
+
+	var r strings.Builder
+	e := json.NewEncoder(&r)
+	e.SetEscapeHTML(false)
+	if e.Encode(redactValue(v)) != nil {
+		return redacted
+	}
+
+	return strings.TrimSuffix(r.String(), "\n")
+}
+
+func redactValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, f := range t {
+			if secretFields[strings.ToLower(k)] && f != nil {
+				t[k] = redacted
+				continue
+			}
+			t[k] = redactValue(f)
+		}
+	case []interface{}:
+		for i, f := range t {
+			t[i] = redactValue(f)
+		}
+	case string:
+		return truncateValue(t)
+	}
+
+	return v
+}
+
+func truncateValue(s string) string {
+	if MaxLoggedValueLength <= 0 || len(s) <= MaxLoggedValueLength {
+		return s
+	}
+
+	return fmt.Sprintf(`%s...(%d bytes)`, s[:MaxLoggedValueLength], len(s))
+}
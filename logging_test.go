@@ -0,0 +1,107 @@
+package goncanode
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/nbah1990/goncanode/entities"
+	"github.com/nbah1990/goncanode/types"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogging(t *testing.T) {
+	t.Run("RedactsSecrets", func(t *testing.T) {
+		var buf bytes.Buffer
+		handler := &NCANodeV3Handler{
+			P12base64: "c2VjcmV0LWtleQ==",
+			P12pass:   "secret-password",
+			Timeout:   time.Second,
+			Logger:    slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})),
+			Api: &mockApiClient{
+				response: []byte(`{"status":200,"message":"Success","xml":"<signedXml></signedXml>"}`),
+			},
+		}
+
+		if _, err := handler.SignWithSecurityHeader(context.Background(), "<xml></xml>", ``); err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+
+		out := buf.String()
+		if strings.Contains(out, "c2VjcmV0LWtleQ==") || strings.Contains(out, "secret-password") {
+			t.Errorf("Expected key material to be redacted, got: %s", out)
+		}
+
+		records := strings.Split(strings.TrimSpace(out), "\n")
+		if len(records) != 2 {
+			t.Fatalf("Expected call and payload records, got: %s", out)
+		}
+
+		var call map[string]interface{}
+		if err := json.Unmarshal([]byte(records[0]), &call); err != nil {
+			t.Fatal(err)
+		}
+		if call["level"] != "INFO" || call["op"] != "SignXml" || call["endpoint"] != "/wsse/sign" || call["status"] != float64(200) || call["version"] != "3.0" {
+			t.Errorf("Unexpected call record: %s", records[0])
+		}
+		if !strings.Contains(records[1], `\"key\":\"[REDACTED]\"`) || !strings.Contains(records[1], "signedXml") {
+			t.Errorf("Unexpected payload record: %s", records[1])
+		}
+	})
+
+	t.Run("InfoLevelHasNoBodies", func(t *testing.T) {
+		var buf bytes.Buffer
+		handler := &NCANodeV3Handler{
+			P12base64: "base64string",
+			P12pass:   "password",
+			Timeout:   time.Second,
+			Logger:    slog.New(slog.NewJSONHandler(&buf, nil)),
+			Api:       &mockApiClient{err: errors.New("connection refused")},
+		}
+
+		if _, err := handler.SignWithSecurityHeader(context.Background(), "<document></document>", ``); err == nil {
+			t.Fatal("Expected error, got nil")
+		}
+
+		out := buf.String()
+		if strings.Contains(out, "document") || strings.Count(out, "\n") != 1 {
+			t.Errorf("Expected a single record without bodies, got: %s", out)
+		}
+		if !strings.Contains(out, `"level":"ERROR"`) || !strings.Contains(out, "connection refused") {
+			t.Errorf("Expected error record, got: %s", out)
+		}
+	})
+}
+
+func TestRedactPayload(t *testing.T) {
+	old := MaxLoggedValueLength
+	MaxLoggedValueLength = 8
+	defer func() { MaxLoggedValueLength = old }()
+
+	got := redactPayload([]byte(`{"version":"2.0","params":{"p12array":[{"p12":"a2V5","Password":"pass"}],"xml":"<document></document>"}}`))
+	expected := `{"params":{"p12array":[{"Password":"[REDACTED]","p12":"[REDACTED]"}],"xml":"<documen...(21 bytes)"},"version":"2.0"}`
+	if got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+
+	if got := redactPayload([]byte(`not a json body`)); got != `not a js...(15 bytes)` {
+		t.Errorf("Unexpected non-JSON payload: %s", got)
+	}
+}
+
+func TestSignParams_LogValue(t *testing.T) {
+	var buf bytes.Buffer
+	slog.New(slog.NewTextHandler(&buf, nil)).Info("sign", "request", entities.SignRequest{
+		Version:          "1.0",
+		Method:           "XML.signWithSecurityHeader",
+		TspHashAlgorithm: types.GOST34311,
+		Params:           entities.SignParams{P12: "a2V5", Password: "s3cr3t", Xml: "<xml/>"},
+	})
+
+	if out := buf.String(); strings.Contains(out, "a2V5") || strings.Contains(out, "s3cr3t") || !strings.Contains(out, "request.params.p12=[REDACTED]") {
+		t.Errorf("Expected redacted params, got: %s", out)
+	}
+}
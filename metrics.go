@@ -0,0 +1,28 @@
+package goncanode
+
+import (
+	"github.com/nbah1990/goncanode/metrics"
+	"github.com/nbah1990/goncanode/types"
+	"strconv"
+	"time"
+)
+
+func recordCall(m metrics.Collector, v types.Version, op string, endpoint string, start time.Time, status int, err error) {
+	if m == nil {
+		return
+	}
+
+	m.ObserveHistogram(metrics.CallDuration, time.Since(start).Seconds(), metrics.Labels{
+		`version`:   string(v),
+		`operation`: op,
+		`endpoint`:  endpoint,
+	})
+
+	m.AddCounter(metrics.CallsTotal, 1, metrics.Labels{
+		`version`:     string(v),
+		`operation`:   op,
+		`endpoint`:    endpoint,
+		`status`:      strconv.Itoa(callStatus(status, err)),
+		`error_class`: ErrorClass(err),
+	})
+}
@@ -0,0 +1,28 @@
+// Package metrics declares the collector goncanode reports call counts and latencies to
+// and provides a dependency-free Prometheus text format adapter.
+package metrics
+
+type Labels map[string]string
+
+type Collector interface {
+	AddCounter(name string, value float64, labels Labels)
+	ObserveHistogram(name string, value float64, labels Labels)
+}
+
+const (
+	// CallsTotal counts handler calls by version, operation, endpoint, status and error_class.
+	CallsTotal = `goncanode_calls_total`
+	// CallDuration observes handler call latency in seconds by version, operation and endpoint.
+	CallDuration = `goncanode_call_duration_seconds`
+	// HTTPRequestsTotal counts http exchanges by node, path, method and code.
+	HTTPRequestsTotal = `goncanode_http_requests_total`
+	// HTTPRequestDuration observes http exchange latency in seconds by node, path and method.
+	HTTPRequestDuration = `goncanode_http_request_duration_seconds`
+)
+
+var help = map[string]string{
+	CallsTotal:          `NCANode calls made by goncanode handlers.`,
+	CallDuration:        `Latency of NCANode calls made by goncanode handlers.`,
+	HTTPRequestsTotal:   `HTTP requests sent to NCANode.`,
+	HTTPRequestDuration: `Latency of HTTP requests sent to NCANode.`,
+}
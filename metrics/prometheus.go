@@ -0,0 +1,182 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// Prometheus keeps collected metrics in memory and serves them in the Prometheus text exposition format.
+type Prometheus struct {
+	Buckets []float64
+
+	mu         sync.Mutex
+	counters   map[string]map[string]float64
+	histograms map[string]map[string]*histogram
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func NewPrometheus() *Prometheus {
+	return &Prometheus{Buckets: DefaultBuckets}
+}
+
+func (p *Prometheus) AddCounter(name string, value float64, labels Labels) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.counters == nil {
+		p.counters = map[string]map[string]float64{}
+	}
+	if p.counters[name] == nil {
+		p.counters[name] = map[string]float64{}
+	}
+
+	p.counters[name][formatLabels(labels)] += value
+}
+
+func (p *Prometheus) ObserveHistogram(name string, value float64, labels Labels) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.histograms == nil {
+		p.histograms = map[string]map[string]*histogram{}
+	}
+	if p.histograms[name] == nil {
+		p.histograms[name] = map[string]*histogram{}
+	}
+
+	key := formatLabels(labels)
+	h := p.histograms[name][key]
+	if h == nil {
+		h = &histogram{counts: make([]uint64, len(p.buckets()))}
+		p.histograms[name][key] = h
+	}
+
+	for i, b := range p.buckets() {
+		if value <= b {
+			h.counts[i]++
+		}
+	}
+	h.sum += value
+	h.count++
+}
+
+func (p *Prometheus) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = p.Write(w)
+}
+
+// Write renders every metric sorted by name and labels.
+func (p *Prometheus) Write(w io.Writer) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var b strings.Builder
+
+	for _, name := range sortedKeys(p.counters) {
+		writeHeader(&b, name, `counter`)
+		for _, l := range sortedKeys(p.counters[name]) {
+			fmt.Fprintf(&b, "%s%s %s\n", name, l, formatFloat(p.counters[name][l]))
+		}
+	}
+
+	for _, name := range sortedKeys(p.histograms) {
+		writeHeader(&b, name, `histogram`)
+		for _, l := range sortedKeys(p.histograms[name]) {
+			h := p.histograms[name][l]
+			for i, bucket := range p.buckets() {
+				fmt.Fprintf(&b, "%s_bucket%s %d\n", name, withLabel(l, `le`, formatFloat(bucket)), h.counts[i])
+			}
+			fmt.Fprintf(&b, "%s_bucket%s %d\n", name, withLabel(l, `le`, `+Inf`), h.count)
+			fmt.Fprintf(&b, "%s_sum%s %s\n", name, l, formatFloat(h.sum))
+			fmt.Fprintf(&b, "%s_count%s %d\n", name, l, h.count)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
+
+func (p *Prometheus) buckets() []float64 {
+	if len(p.Buckets) == 0 {
+		return DefaultBuckets
+	}
+
+	return p.Buckets
+}
+
+func writeHeader(b *strings.Builder, name string, kind string) {
+	if h, ok := help[name]; ok {
+		fmt.Fprintf(b, "# HELP %s %s\n", name, h)
+	}
+	fmt.Fprintf(b, "# TYPE %s %s\n", name, kind)
+}
+
+// formatLabels renders labels sorted by name, it doubles as the series key.
+func formatLabels(labels Labels) string {
+	if len(labels) == 0 {
+		return ``
+	}
+
+	names := make([]string, 0, len(labels))
+	for k := range labels {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, k := range names {
+		parts = append(parts, fmt.Sprintf(`%s="%s"`, k, escapeLabel(labels[k])))
+	}
+
+	return `{` + strings.Join(parts, `,`) + `}`
+}
+
+func withLabel(labels string, name string, value string) string {
+	l := fmt.Sprintf(`%s="%s"`, name, value)
+	if labels == `` {
+		return `{` + l + `}`
+	}
+
+	return labels[:len(labels)-1] + `,` + l + `}`
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return `+Inf`
+	case math.IsInf(v, -1):
+		return `-Inf`
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPrometheus(t *testing.T) {
+	p := NewPrometheus()
+	p.Buckets = []float64{0.1, 1}
+
+	p.AddCounter(CallsTotal, 1, Labels{"operation": "SignXml", "status": "200"})
+	p.AddCounter(CallsTotal, 2, Labels{"status": "200", "operation": "SignXml"})
+	p.AddCounter("custom_total", 1, Labels{"path": "a\"b\\c\nd"})
+	p.ObserveHistogram(CallDuration, 0.05, Labels{"operation": "SignXml"})
+	p.ObserveHistogram(CallDuration, 0.5, Labels{"operation": "SignXml"})
+	p.ObserveHistogram(CallDuration, 3, Labels{"operation": "SignXml"})
+
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Unexpected content type: %s", ct)
+	}
+
+	expected := `# TYPE custom_total counter
+custom_total{path="a\"b\\c\nd"} 1
+# HELP goncanode_calls_total NCANode calls made by goncanode handlers.
+# TYPE goncanode_calls_total counter
+goncanode_calls_total{operation="SignXml",status="200"} 3
+# HELP goncanode_call_duration_seconds Latency of NCANode calls made by goncanode handlers.
+# TYPE goncanode_call_duration_seconds histogram
+goncanode_call_duration_seconds_bucket{operation="SignXml",le="0.1"} 1
+goncanode_call_duration_seconds_bucket{operation="SignXml",le="1"} 2
+goncanode_call_duration_seconds_bucket{operation="SignXml",le="+Inf"} 3
+goncanode_call_duration_seconds_sum{operation="SignXml"} 3.55
+goncanode_call_duration_seconds_count{operation="SignXml"} 3
+`
+	if got := rec.Body.String(); got != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestPrometheus_NoLabels(t *testing.T) {
+	p := NewPrometheus()
+	p.AddCounter("plain_total", 1, nil)
+
+	var b strings.Builder
+	if err := p.Write(&b); err != nil {
+		t.Fatalf("Expected no errors, got: %v", err)
+	}
+	if b.String() != "# TYPE plain_total counter\nplain_total 1\n" {
+		t.Errorf("Unexpected output: %q", b.String())
+	}
+}
@@ -0,0 +1,42 @@
+package goncanode
+
+import (
+	"context"
+	"github.com/nbah1990/goncanode/metrics"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+	p := metrics.NewPrometheus()
+	handler := &NCANodeV1Handler{
+		P12base64: "base64string",
+		Timeout:   time.Second,
+		Metrics:   p,
+		Api:       &mockApiClientV1{response: []byte(`{"status":0,"message":"","result":{"xml":"<signedXml></signedXml>"}}`)},
+	}
+
+	if _, err := handler.SignWithSecurityHeader(context.Background(), "<xml></xml>", ``); err != nil {
+		t.Fatalf("Expected no errors, got: %v", err)
+	}
+
+	handler.Api = &mockApiClientV1{response: []byte(`{"status":-1,"message":"Certificate revoked"}`)}
+	if _, err := handler.SignWithSecurityHeader(context.Background(), "<xml></xml>", ``); err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+
+	var b strings.Builder
+	_ = p.Write(&b)
+	out := b.String()
+
+	for _, s := range []string{
+		`goncanode_calls_total{endpoint="",error_class="",operation="XML.signWithSecurityHeader",status="0",version="1.0"} 1`,
+		`goncanode_calls_total{endpoint="",error_class="revoked_certificate",operation="XML.signWithSecurityHeader",status="-1",version="1.0"} 1`,
+		`goncanode_call_duration_seconds_count{endpoint="",operation="XML.signWithSecurityHeader",version="1.0"} 2`,
+	} {
+		if !strings.Contains(out, s) {
+			t.Errorf("Expected %s in:\n%s", s, out)
+		}
+	}
+}
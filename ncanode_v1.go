@@ -3,58 +3,501 @@ package goncanode
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"github.com/nbah1990/goncanode/api"
 	"github.com/nbah1990/goncanode/entities"
+	"github.com/nbah1990/goncanode/metrics"
+	"github.com/nbah1990/goncanode/tracing"
 	"github.com/nbah1990/goncanode/types"
+	"io"
+	"log/slog"
 	"net/http"
 	"time"
 )
 
+// v1StatusOK is what NCANode 1.x returns on success, some builds answer with http.StatusOK instead.
+const v1StatusOK = 0
+
 type NCANodeV1Handler struct {
 	P12base64 string
 	P12pass   string
-	Timeout   time.Duration
+	// Keys supplies the key per call instead of P12base64 and P12pass when set.
+	Keys entities.KeyProvider
+	// KeyAlias selects the key of a container holding several, SignKeyAlias overrides it per call.
+	KeyAlias string
+	Timeout  time.Duration
+	// Logger reports every call, nothing is logged when nil.
+	Logger *slog.Logger
+	// Tracer gets a span per call, nothing is traced when nil.
+	Tracer tracing.Tracer
+	// Metrics gets call counts and latencies, nothing is collected when nil.
+	Metrics metrics.Collector
 
 	Api api.IClient
 }
 
-func (h *NCANodeV1Handler) SignWithSecurityHeader(ctx context.Context, xml string, hashAlgorithm types.HashAlgorithm) (result entities.Response, err error) {
+type v1Status struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+type v1RawSignParams struct {
+	P12       string `json:"p12"`
+	Password  string `json:"password"`
+	Alias     string `json:"alias,omitempty"`
+	Raw       string `json:"raw"`
+	CreateTsp bool   `json:"createTsp"`
+
+	UseTsaPolicy     types.TsaPolicy     `json:"useTsaPolicy,omitempty"`
+	TspHashAlgorithm types.HashAlgorithm `json:"tspHashAlgorithm,omitempty"`
+}
+
+type v1RawSignResponse struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+	Result  struct {
+		Cms string `json:"cms"`
+	} `json:"result"`
+}
+
+type v1RawVerifyParams struct {
+	Cms        string `json:"cms"`
+	VerifyOcsp bool   `json:"verifyOcsp"`
+	VerifyCrl  bool   `json:"verifyCrl"`
+}
+
+type v1RawVerifyResponse struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+	Result  struct {
+		Valid bool           `json:"valid"`
+		Cert  *v1Certificate `json:"cert"`
+		Tsp   *struct {
+			GenTime *nodeTime `json:"genTime"`
+		} `json:"tsp"`
+	} `json:"result"`
+}
+
+type v1RawExtractParams struct {
+	Cms string `json:"cms"`
+}
+
+type v1RawExtractResponse struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+	Result  struct {
+		Data string `json:"data"`
+	} `json:"result"`
+}
+
+type v1X509InfoParams struct {
+	Cert       string `json:"cert"`
+	VerifyOcsp bool   `json:"verifyOcsp"`
+	VerifyCrl  bool   `json:"verifyCrl"`
+}
+
+type v1Pkcs12InfoParams struct {
+	P12        string `json:"p12"`
+	Password   string `json:"password"`
+	Alias      string `json:"alias,omitempty"`
+	VerifyOcsp bool   `json:"verifyOcsp"`
+	VerifyCrl  bool   `json:"verifyCrl"`
+}
+
+type v1CertificateResponse struct {
+	Status  int           `json:"status"`
+	Message string        `json:"message"`
+	Result  v1Certificate `json:"result"`
+}
+
+type v1Pkcs12AliasesParams struct {
+	P12      string `json:"p12"`
+	Password string `json:"password"`
+}
+
+type v1Pkcs12AliasesResponse struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+	Result  struct {
+		Aliases []string `json:"aliases"`
+	} `json:"result"`
+}
+
+type v1XmlVerifyParams struct {
+	Xml        string `json:"xml"`
+	VerifyOcsp bool   `json:"verifyOcsp"`
+	VerifyCrl  bool   `json:"verifyCrl"`
+}
+
+type v1XmlVerifyResponse struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+	Result  struct {
+		Valid bool           `json:"valid"`
+		Cert  *v1Certificate `json:"cert"`
+	} `json:"result"`
+}
+
+type v1Certificate struct {
+	Valid        bool            `json:"valid"`
+	NotBefore    nodeTime        `json:"notBefore"`
+	NotAfter     nodeTime        `json:"notAfter"`
+	KeyUsage     string          `json:"keyUsage"`
+	SerialNumber string          `json:"serialNumber"`
+	SignAlg      string          `json:"signAlg"`
+	Policies     []string        `json:"policies"`
+	KeyUser      []string        `json:"keyUser"`
+	Subject      nodeSubject     `json:"subject"`
+	Issuer       nodeSubject     `json:"issuer"`
+	Cert         string          `json:"cert"`
+	Chain        []v1Certificate `json:"chain"`
+	Ocsp         *v1Revocation   `json:"ocsp"`
+	Crl          *v1Revocation   `json:"crl"`
+}
+
+type v1Revocation struct {
+	Status           string    `json:"status"`
+	RevokationTime   *nodeTime `json:"revokationTime"`
+	RevokationReason string    `json:"revokationReason"`
+}
+
+func (c v1Certificate) entity() entities.Certificate {
+	r := entities.Certificate{
+		Valid:        c.Valid,
+		SerialNumber: c.SerialNumber,
+		NotBefore:    c.NotBefore.Time,
+		NotAfter:     c.NotAfter.Time,
+		KeyUsage:     c.KeyUsage,
+		KeyUser:      c.KeyUser,
+		SignAlg:      c.SignAlg,
+		Policies:     c.Policies,
+		Subject:      c.Subject.entity(),
+		Issuer:       c.Issuer.entity(),
+		Certificate:  c.Cert,
+	}
+
+	if c.Ocsp != nil {
+		r.Revocations = append(r.Revocations, c.Ocsp.entity(types.OCSP))
+	}
+	if c.Crl != nil {
+		r.Revocations = append(r.Revocations, c.Crl.entity(types.CRL))
+	}
+
+	return r
+}
+
+func (r v1Revocation) entity(by types.RevocationCheck) entities.Revocation {
+	return entities.Revocation{
+		By:             by,
+		Revoked:        r.Status == `REVOKED`,
+		RevocationTime: r.RevokationTime.ptr(),
+		Reason:         r.RevokationReason,
+	}
+}
+
+func (h *NCANodeV1Handler) SignWithSecurityHeader(ctx context.Context, xml string, hashAlgorithm types.HashAlgorithm, opts ...SignOption) (result entities.Response, err error) {
+	o := signOptions(opts)
+	if err = unsupported(`XML.signWithSecurityHeader`, `v1`, feature{`TrimXml`, o.TrimXml}, feature{`WithTsp`, o.WithTsp}); err != nil {
+		return
+	}
+	if o.HashAlgorithm != `` {
+		hashAlgorithm = o.HashAlgorithm
+	}
+	if err = checkHash(`XML.signWithSecurityHeader`, hashAlgorithm, types.NCAnodeV10); err != nil {
+		return
+	}
+	ctx = o.context(ctx)
+
+	k, err := h.key(ctx, `XML.signWithSecurityHeader`)
+	if err != nil {
+		return
+	}
+
 	r := &entities.SignRequest{
 		Version:          "1.0",
 		Method:           "XML.signWithSecurityHeader",
 		TspHashAlgorithm: hashAlgorithm,
 		Params: entities.SignParams{
-			P12:      h.P12base64,
-			Password: h.P12pass,
+			P12:      k.P12base64,
+			Password: k.Password,
 			Xml:      xml,
+			Alias:    k.Alias,
 		},
 	}
 
 	return h.ExecuteRequest(ctx, r)
 }
 
-func (h *NCANodeV1Handler) ExecuteRequest(ctx context.Context, r *entities.SignRequest) (result entities.Response, err error) {
-	ctx, cancel := context.WithTimeout(ctx, h.Timeout)
-	defer cancel()
+func (h *NCANodeV1Handler) SignCms(ctx context.Context, data io.Reader, detached bool, opts ...SignOption) (result entities.CmsResult, err error) {
+	b, err := readCmsData(`RAW.sign`, data)
+	if err != nil {
+		return
+	}
+
+	return h.SignCmsBytes(ctx, b, detached, opts...)
+}
 
-	rs, err := json.Marshal(r)
+// SignCmsBytes always produces an attached CMS: RAW.sign in NCANode v1 can't omit the signed content.
+func (h *NCANodeV1Handler) SignCmsBytes(ctx context.Context, data []byte, detached bool, opts ...SignOption) (result entities.CmsResult, err error) {
+	o := signOptions(opts)
+	if err = unsupported(`RAW.sign`, `v1`, feature{`detached cms`, detached}, feature{`TrimXml`, o.TrimXml}); err != nil {
+		return
+	}
+	if err = checkHash(`RAW.sign`, o.HashAlgorithm, types.NCAnodeV10); err != nil {
+		return
+	}
+	ctx = o.context(ctx)
+
+	k, err := h.key(ctx, `RAW.sign`)
 	if err != nil {
 		return
 	}
 
-	rb := bytes.NewBuffer(rs)
+	p := v1RawSignParams{
+		P12:       k.P12base64,
+		Password:  k.Password,
+		Alias:     k.Alias,
+		Raw:       base64.StdEncoding.EncodeToString(data),
+		CreateTsp: o.WithTsp,
 
-	resp, err := h.Api.Request(ctx, http.MethodPost, ``, rb)
+		UseTsaPolicy:     o.TsaPolicy,
+		TspHashAlgorithm: o.HashAlgorithm,
+	}
+
+	var respStruct v1RawSignResponse
+	err = h.call(ctx, `RAW.sign`, p, &respStruct)
+	if err != nil {
+		return
+	}
+
+	return decodeCms(`RAW.sign`, respStruct.Result.Cms)
+}
+
+func (h *NCANodeV1Handler) VerifyCms(ctx context.Context, cms []byte, data []byte, checks ...types.RevocationCheck) (result entities.CmsVerifyResult, err error) {
+	ctx = api.WithIdempotent(ctx)
+
+	if data != nil {
+		return result, &UnsupportedError{Op: `RAW.verify`, Feature: `detached cms`, Version: `v1`}
+	}
+
+	p := v1RawVerifyParams{
+		Cms:        base64.StdEncoding.EncodeToString(cms),
+		VerifyOcsp: hasRevocationCheck(checks, types.OCSP),
+		VerifyCrl:  hasRevocationCheck(checks, types.CRL),
+	}
+
+	var respStruct v1RawVerifyResponse
+	err = h.call(ctx, `RAW.verify`, p, &respStruct)
 	if err != nil {
 		return
 	}
 
+	result.Valid = respStruct.Result.Valid
+	if c := respStruct.Result.Cert; c != nil {
+		chain := []entities.Certificate{c.entity()}
+		for _, cc := range c.Chain {
+			chain = append(chain, cc.entity())
+		}
+
+		var signingTime *time.Time
+		if respStruct.Result.Tsp != nil {
+			signingTime = respStruct.Result.Tsp.GenTime.ptr()
+		}
+
+		result.Signers = append(result.Signers, cmsSigner(chain, signingTime))
+	}
+
+	return result, nil
+}
+
+func (h *NCANodeV1Handler) ExtractCms(ctx context.Context, cms []byte) (data []byte, err error) {
+	ctx = api.WithIdempotent(ctx)
+
+	p := v1RawExtractParams{
+		Cms: base64.StdEncoding.EncodeToString(cms),
+	}
+
+	var respStruct v1RawExtractResponse
+	err = h.call(ctx, `RAW.extract`, p, &respStruct)
+	if err != nil {
+		return
+	}
+
+	return decodeCmsData(`RAW.extract`, respStruct.Result.Data)
+}
+
+func (h *NCANodeV1Handler) VerifyXml(ctx context.Context, xml string, checks ...types.RevocationCheck) (result entities.VerifyResult, err error) {
+	ctx = api.WithIdempotent(ctx)
+
+	p := v1XmlVerifyParams{
+		Xml:        xml,
+		VerifyOcsp: hasRevocationCheck(checks, types.OCSP),
+		VerifyCrl:  hasRevocationCheck(checks, types.CRL),
+	}
+
+	var respStruct v1XmlVerifyResponse
+	err = h.call(ctx, `XML.verify`, p, &respStruct)
+	if err != nil {
+		return
+	}
+
+	result.Valid = respStruct.Result.Valid
+	if respStruct.Result.Cert != nil {
+		result.Signers = append(result.Signers, respStruct.Result.Cert.entity())
+	}
+
+	return result, nil
+}
+
+// VerifyWithSecurityHeader uses XML.verify as NCANode v1 has no dedicated WS-Security verification method.
+func (h *NCANodeV1Handler) VerifyWithSecurityHeader(ctx context.Context, xml string, checks ...types.RevocationCheck) (result entities.VerifyResult, err error) {
+	return h.VerifyXml(ctx, xml, checks...)
+}
+
+func (h *NCANodeV1Handler) X509Info(ctx context.Context, cert []byte, checks ...types.RevocationCheck) (result entities.Certificate, err error) {
+	ctx = api.WithIdempotent(ctx)
+
+	p := v1X509InfoParams{
+		Cert:       base64.StdEncoding.EncodeToString(cert),
+		VerifyOcsp: hasRevocationCheck(checks, types.OCSP),
+		VerifyCrl:  hasRevocationCheck(checks, types.CRL),
+	}
+
+	var respStruct v1CertificateResponse
+	err = h.call(ctx, `X509.info`, p, &respStruct)
+	if err != nil {
+		return
+	}
+
+	return respStruct.Result.entity(), nil
+}
+
+func (h *NCANodeV1Handler) Pkcs12Info(ctx context.Context, checks ...types.RevocationCheck) (result entities.Certificate, err error) {
+	ctx = api.WithIdempotent(ctx)
+
+	k, err := h.key(ctx, `PKCS12.info`)
+	if err != nil {
+		return
+	}
+
+	p := v1Pkcs12InfoParams{
+		P12:        k.P12base64,
+		Password:   k.Password,
+		Alias:      k.Alias,
+		VerifyOcsp: hasRevocationCheck(checks, types.OCSP),
+		VerifyCrl:  hasRevocationCheck(checks, types.CRL),
+	}
+
+	var respStruct v1CertificateResponse
+	err = h.call(ctx, `PKCS12.info`, p, &respStruct)
+	if err != nil {
+		return
+	}
+
+	return respStruct.Result.entity(), nil
+}
+
+func (h *NCANodeV1Handler) Pkcs12Aliases(ctx context.Context) (result []entities.KeyAlias, err error) {
+	ctx = api.WithIdempotent(ctx)
+
+	k, err := h.key(ctx, `PKCS12.aliases`)
+	if err != nil {
+		return
+	}
+
+	p := v1Pkcs12AliasesParams{
+		P12:      k.P12base64,
+		Password: k.Password,
+	}
+
+	var respStruct v1Pkcs12AliasesResponse
+	err = h.call(ctx, `PKCS12.aliases`, p, &respStruct)
+	if err != nil {
+		return
+	}
+
+	for _, a := range respStruct.Result.Aliases {
+		result = append(result, entities.KeyAlias{Alias: a})
+	}
+
+	return result, nil
+}
+
+func (h *NCANodeV1Handler) ExecuteRequest(ctx context.Context, r *entities.SignRequest) (result entities.Response, err error) {
 	var respStruct entities.Response
-	err = json.Unmarshal(resp, &respStruct)
+	err = h.execute(ctx, r.Method, r, &respStruct)
 	if err != nil {
 		return
 	}
 
 	return respStruct, nil
 }
+
+func (h *NCANodeV1Handler) key(ctx context.Context, op string) (entities.Key, error) {
+	start := time.Now()
+	k, err := resolveKey(ctx, op, h.Keys, h.P12base64, h.P12pass, h.KeyAlias)
+	if err != nil {
+		failCall(ctx, h.Logger, h.Tracer, h.Metrics, types.NCAnodeV10, op, ``, start, err)
+	}
+
+	return k, err
+}
+
+func (h *NCANodeV1Handler) call(ctx context.Context, method string, params interface{}, resp interface{}) error {
+	return h.execute(ctx, method, rpcRequest{Version: string(types.NCAnodeV10), Method: method, Params: params}, resp)
+}
+
+func (h *NCANodeV1Handler) execute(ctx context.Context, op string, r interface{}, resp interface{}) (err error) {
+	start := time.Now()
+	ctx, span := startSpan(ctx, h.Tracer, types.NCAnodeV10, op, ``)
+
+	ctx, cancel := context.WithTimeout(ctx, callTimeout(ctx, h.Timeout))
+	defer cancel()
+
+	var rs, b []byte
+	var status v1Status
+	defer func() {
+		endSpan(span, start, status.Status, err)
+		recordCall(h.Metrics, types.NCAnodeV10, op, ``, start, status.Status, err)
+		logCall(ctx, h.Logger, types.NCAnodeV10, op, ``, start, status.Status, rs, b, err)
+	}()
+
+	rs, err = json.Marshal(r)
+	if err != nil {
+		return encodeError(op, err)
+	}
+
+	rb := bytes.NewBuffer(rs)
+
+	b, err = h.Api.Request(ctx, http.MethodPost, ``, rb)
+	if err != nil {
+		return requestError(op, err, b)
+	}
+
+	err = json.Unmarshal(b, &status)
+	if err != nil {
+		return &DecodeError{Op: op, What: `http response json`, Err: err}
+	}
+
+	if status.Status != v1StatusOK && status.Status != http.StatusOK {
+		return &NodeError{Op: op, Status: status.Status, Message: status.Message}
+	}
+
+	err = json.Unmarshal(b, resp)
+	if err != nil {
+		return &DecodeError{Op: op, What: `http response json`, Err: err}
+	}
+
+	return nil
+}
+
+func hasRevocationCheck(checks []types.RevocationCheck, c types.RevocationCheck) bool {
+	for _, v := range checks {
+		if v == c {
+			return true
+		}
+	}
+
+	return false
+}
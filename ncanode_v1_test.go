@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"strings"
 	"testing"
 
 	"github.com/nbah1990/goncanode/entities"
@@ -13,9 +14,14 @@ import (
 type mockApiClientV1 struct {
 	response []byte
 	err      error
+
+	url  string
+	body []byte
 }
 
-func (m *mockApiClientV1) Request(_ context.Context, _ string, _ string, _ *bytes.Buffer) ([]byte, error) {
+func (m *mockApiClientV1) Request(_ context.Context, _ string, url string, data *bytes.Buffer) ([]byte, error) {
+	m.url = url
+	m.body = data.Bytes()
 	return m.response, m.err
 }
 
@@ -63,19 +69,24 @@ func TestNCANodeV1Handler_SignWithSecurityHeader(t *testing.T) {
 	})
 
 	t.Run("ApiRequestError", func(t *testing.T) {
+		reqErr := errors.New("request error")
 		handler := &NCANodeV1Handler{
 			P12base64: "base64string",
 			P12pass:   "password",
 			Api: &mockApiClientV1{
-				err: errors.New("request error"),
+				err: reqErr,
 			},
 		}
 
 		ctx := context.Background()
 		_, err := handler.SignWithSecurityHeader(ctx, "<xml></xml>", types.GOST34311GT)
-		if err == nil || err.Error() != "request error" {
+		var transportErr *TransportError
+		if !errors.As(err, &transportErr) || !errors.Is(err, reqErr) {
 			t.Errorf("Expected API request error, got: %v", err)
 		}
+		if err == nil || err.Error() != "XML.signWithSecurityHeader: http request error: request error" {
+			t.Errorf("Expected API request error message, got: %v", err)
+		}
 	})
 
 	t.Run("JsonUnmarshalError", func(t *testing.T) {
@@ -104,15 +115,217 @@ func TestNCANodeV1Handler_SignWithSecurityHeader(t *testing.T) {
 		}
 
 		ctx := context.Background()
-		result, err := handler.SignWithSecurityHeader(ctx, "<xml></xml>", types.GOST34311GT)
+		_, err := handler.SignWithSecurityHeader(ctx, "<xml></xml>", types.GOST34311GT)
+		var nodeErr *NodeError
+		if !errors.As(err, &nodeErr) {
+			t.Fatalf("Expected *NodeError, got: %v", err)
+		}
+		if nodeErr.Status != 400 {
+			t.Errorf("Expected status 400, got: %d", nodeErr.Status)
+		}
+		if nodeErr.Message != "Bad Request" {
+			t.Errorf("Expected message 'Bad Request', got: %s", nodeErr.Message)
+		}
+	})
+
+	t.Run("InvalidPassword", func(t *testing.T) {
+		handler := &NCANodeV1Handler{
+			P12base64: "base64string",
+			P12pass:   "password",
+			Api: &mockApiClientV1{
+				response: []byte(`{"status":-1,"message":"Invalid password or keystore is corrupted"}`),
+			},
+		}
+
+		ctx := context.Background()
+		_, err := handler.SignWithSecurityHeader(ctx, "<xml></xml>", types.GOST34311GT)
+		if !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Expected ErrInvalidKey, got: %v", err)
+		}
+	})
+}
+
+func TestNCANodeV1Handler_VerifyXml(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		client := &mockApiClientV1{
+			response: []byte(`{"status":0,"message":"","result":{"valid":true,"cert":{"valid":true,"notBefore":"2023-01-10 09:00:00","notAfter":"2024-01-10 09:00:00","serialNumber":"1a2b","subject":{"bin":"123456789012"},"ocsp":{"status":"REVOKED","revokationTime":"2023-06-01 10:00:00","revokationReason":"keyCompromise"},"crl":{"status":"ACTIVE"}}}}`),
+		}
+		handler := &NCANodeV1Handler{Api: client}
+
+		result, err := handler.VerifyXml(context.Background(), "<xml></xml>", types.OCSP, types.CRL)
+		if err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+		if !strings.Contains(string(client.body), `"method":"XML.verify"`) || !strings.Contains(string(client.body), `"verifyOcsp":true,"verifyCrl":true`) {
+			t.Errorf("Unexpected request body: %s", client.body)
+		}
+		if !result.Valid || len(result.Signers) != 1 {
+			t.Fatalf("Unexpected result: %+v", result)
+		}
+		s := result.Signers[0]
+		if s.Subject.BIN != "123456789012" {
+			t.Errorf("Incorrect signer, got: %+v", s)
+		}
+		if len(s.Revocations) != 2 {
+			t.Fatalf("Expected 2 revocation checks, got: %+v", s.Revocations)
+		}
+		if !s.Revocations[0].Revoked || s.Revocations[0].RevocationTime == nil || s.Revocations[0].Reason != "keyCompromise" {
+			t.Errorf("Expected revoked OCSP status, got: %+v", s.Revocations[0])
+		}
+		if s.Revocations[1].By != types.CRL || s.Revocations[1].Revoked {
+			t.Errorf("Expected active CRL status, got: %+v", s.Revocations[1])
+		}
+	})
+
+	t.Run("ApiRequestError", func(t *testing.T) {
+		handler := &NCANodeV1Handler{
+			Api: &mockApiClientV1{
+				err: errors.New("request error"),
+			},
+		}
+
+		_, err := handler.VerifyWithSecurityHeader(context.Background(), "<xml></xml>")
+		if err == nil || err.Error() != "XML.verify: http request error: request error" {
+			t.Errorf("Expected API request error, got: %v", err)
+		}
+	})
+}
+
+func TestNCANodeV1Handler_SignCms(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		client := &mockApiClientV1{
+			response: []byte(`{"status":0,"message":"","result":{"cms":"MIIBAg=="}}`),
+		}
+		handler := &NCANodeV1Handler{P12base64: "base64string", P12pass: "password", Api: client}
+
+		result, err := handler.SignCms(context.Background(), strings.NewReader(`{"a":1}`), false)
 		if err != nil {
-			t.Fatalf("Expected no error, got: %v", err)
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+		if !strings.Contains(string(client.body), `"method":"RAW.sign"`) || !strings.Contains(string(client.body), `"raw":"eyJhIjoxfQ=="`) {
+			t.Errorf("Unexpected request body: %s", client.body)
+		}
+		if !bytes.Equal(result.Der, []byte{0x30, 0x82, 0x01, 0x02}) {
+			t.Errorf("Incorrect cms result, got: %+v", result)
+		}
+	})
+
+	t.Run("Detached", func(t *testing.T) {
+		handler := &NCANodeV1Handler{Api: &mockApiClientV1{}}
+
+		_, err := handler.SignCmsBytes(context.Background(), []byte("data"), true)
+		if err == nil {
+			t.Errorf("Expected detached mode error, got nil")
+		}
+	})
+}
+
+func TestNCANodeV1Handler_VerifyCms(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		client := &mockApiClientV1{
+			response: []byte(`{"status":0,"message":"","result":{"valid":true,"cert":{"valid":true,"serialNumber":"01","chain":[{"valid":true,"serialNumber":"02"}],"ocsp":{"status":"ACTIVE"}},"tsp":{"genTime":"2023-05-01 10:00:00"}}}`),
+		}
+		handler := &NCANodeV1Handler{Api: client}
+
+		result, err := handler.VerifyCms(context.Background(), []byte{0x30}, nil, types.OCSP)
+		if err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+		if !strings.Contains(string(client.body), `"method":"RAW.verify","params":{"cms":"MA==","verifyOcsp":true,"verifyCrl":false}`) {
+			t.Errorf("Unexpected request body: %s", client.body)
+		}
+		if !result.Valid || len(result.Signers) != 1 {
+			t.Fatalf("Unexpected result: %+v", result)
+		}
+		s := result.Signers[0]
+		if s.Certificate.SerialNumber != "01" || len(s.Chain) != 1 || !s.ChainValid {
+			t.Errorf("Incorrect signer chain, got: %+v", s)
+		}
+		if s.SigningTime == nil {
+			t.Errorf("Expected signing time")
+		}
+	})
+
+	t.Run("Detached", func(t *testing.T) {
+		handler := &NCANodeV1Handler{Api: &mockApiClientV1{}}
+
+		_, err := handler.VerifyCms(context.Background(), []byte{0x30}, []byte("data"))
+		if err == nil {
+			t.Errorf("Expected detached mode error, got nil")
+		}
+	})
+}
+
+func TestNCANodeV1Handler_ExtractCms(t *testing.T) {
+	client := &mockApiClientV1{
+		response: []byte(`{"status":0,"message":"","result":{"data":"ZGF0YQ=="}}`),
+	}
+	handler := &NCANodeV1Handler{Api: client}
+
+	data, err := handler.ExtractCms(context.Background(), []byte{0x30})
+	if err != nil {
+		t.Fatalf("Expected no errors, got: %v", err)
+	}
+	if !strings.Contains(string(client.body), `"method":"RAW.extract"`) {
+		t.Errorf("Unexpected request body: %s", client.body)
+	}
+	if string(data) != "data" {
+		t.Errorf("Incorrect data, got: %s", data)
+	}
+}
+
+func TestNCANodeV1Handler_CertificateInfo(t *testing.T) {
+	t.Run("X509Info", func(t *testing.T) {
+		client := &mockApiClientV1{
+			response: []byte(`{"status":0,"message":"","result":{"valid":true,"serialNumber":"01","keyUsage":"SIGN","subject":{"iin":"123456789012"},"notAfter":"2024-01-10 09:00:00","crl":{"status":"ACTIVE"}}}`),
+		}
+		handler := &NCANodeV1Handler{Api: client}
+
+		result, err := handler.X509Info(context.Background(), []byte{0x30}, types.CRL)
+		if err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+		if !strings.Contains(string(client.body), `"method":"X509.info","params":{"cert":"MA==","verifyOcsp":false,"verifyCrl":true}`) {
+			t.Errorf("Unexpected request body: %s", client.body)
+		}
+		if result.Subject.IIN != "123456789012" || result.NotAfter.Year() != 2024 || len(result.Revocations) != 1 {
+			t.Errorf("Incorrect certificate, got: %+v", result)
+		}
+	})
+
+	t.Run("Pkcs12Info", func(t *testing.T) {
+		client := &mockApiClientV1{
+			response: []byte(`{"status":0,"message":"","result":{"valid":true,"serialNumber":"01"}}`),
+		}
+		handler := &NCANodeV1Handler{P12base64: "base64string", P12pass: "password", Api: client}
+
+		result, err := handler.Pkcs12Info(context.Background())
+		if err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+		if !strings.Contains(string(client.body), `"method":"PKCS12.info","params":{"p12":"base64string","password":"password"`) {
+			t.Errorf("Unexpected request body: %s", client.body)
+		}
+		if result.SerialNumber != "01" {
+			t.Errorf("Incorrect certificate, got: %+v", result)
+		}
+	})
+
+	t.Run("Pkcs12Aliases", func(t *testing.T) {
+		client := &mockApiClientV1{
+			response: []byte(`{"status":0,"message":"","result":{"aliases":["auth","sign"]}}`),
+		}
+		handler := &NCANodeV1Handler{Api: client}
+
+		result, err := handler.Pkcs12Aliases(context.Background())
+		if err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
 		}
-		if result.Status != 400 {
-			t.Errorf("Expected status 400, got: %d", result.Status)
+		if !strings.Contains(string(client.body), `"method":"PKCS12.aliases"`) {
+			t.Errorf("Unexpected request body: %s", client.body)
 		}
-		if result.Message != "Bad Request" {
-			t.Errorf("Expected message 'Bad Request', got: %s", result.Message)
+		if len(result) != 2 || result[0].Alias != "auth" {
+			t.Errorf("Incorrect aliases, got: %+v", result)
 		}
 	})
 }
@@ -0,0 +1,437 @@
+package goncanode
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/nbah1990/goncanode/api"
+	"github.com/nbah1990/goncanode/entities"
+	"github.com/nbah1990/goncanode/metrics"
+	"github.com/nbah1990/goncanode/tracing"
+	"github.com/nbah1990/goncanode/types"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// v2StatusOK is the only successful status of NCANode 2.x, every other value is an error code described by the message.
+const v2StatusOK = 0
+
+type NCANodeV2Handler struct {
+	P12base64 string
+	P12pass   string
+	// Keys supplies the key per call instead of P12base64 and P12pass when set.
+	Keys entities.KeyProvider
+	// KeyAlias selects the key of a container holding several, SignKeyAlias overrides it per call.
+	KeyAlias string
+	Timeout  time.Duration
+	// Logger reports every call, nothing is logged when nil.
+	Logger *slog.Logger
+	// Tracer gets a span per call, nothing is traced when nil.
+	Tracer tracing.Tracer
+	// Metrics gets call counts and latencies, nothing is collected when nil.
+	Metrics metrics.Collector
+
+	Api api.IClient
+}
+
+type v2Response struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+func (r *v2Response) response() *v2Response {
+	return r
+}
+
+type v2StatusResponse interface {
+	response() *v2Response
+}
+
+type v2Key struct {
+	P12      string `json:"p12"`
+	Password string `json:"password"`
+	Alias    string `json:"alias,omitempty"`
+}
+
+type v2XmlSignParams struct {
+	P12array         []v2Key             `json:"p12array"`
+	Xml              string              `json:"xml"`
+	TspHashAlgorithm types.HashAlgorithm `json:"tspHashAlgorithm,omitempty"`
+}
+
+type v2XmlSignResponse struct {
+	v2Response
+	Xml string `json:"xml"`
+}
+
+type v2XmlVerifyParams struct {
+	Xml        string `json:"xml"`
+	VerifyOcsp bool   `json:"verifyOcsp"`
+	VerifyCrl  bool   `json:"verifyCrl"`
+}
+
+type v2XmlVerifyResponse struct {
+	v2Response
+	Valid   bool            `json:"valid"`
+	Signers []v1Certificate `json:"signers"`
+}
+
+type v2CmsSignParams struct {
+	P12array []v2Key `json:"p12array"`
+	Data     string  `json:"data"`
+	Detached bool    `json:"detached"`
+	WithTsp  bool    `json:"withTsp"`
+
+	TsaPolicy        types.TsaPolicy     `json:"tsaPolicy,omitempty"`
+	TspHashAlgorithm types.HashAlgorithm `json:"tspHashAlgorithm,omitempty"`
+}
+
+type v2CmsResponse struct {
+	v2Response
+	Cms string `json:"cms"`
+}
+
+type v2CmsVerifyParams struct {
+	Cms        string  `json:"cms"`
+	Data       *string `json:"data"`
+	VerifyOcsp bool    `json:"verifyOcsp"`
+	VerifyCrl  bool    `json:"verifyCrl"`
+}
+
+type v2CmsVerifyResponse struct {
+	v2Response
+	Valid   bool `json:"valid"`
+	Signers []struct {
+		Chain []v1Certificate `json:"chain"`
+		Tsp   *struct {
+			GenTime *nodeTime `json:"genTime"`
+		} `json:"tsp"`
+	} `json:"signers"`
+}
+
+type v2CmsExtractParams struct {
+	Cms string `json:"cms"`
+}
+
+type v2CmsExtractResponse struct {
+	v2Response
+	Data string `json:"data"`
+}
+
+type v2X509InfoParams struct {
+	Cert       string `json:"cert"`
+	VerifyOcsp bool   `json:"verifyOcsp"`
+	VerifyCrl  bool   `json:"verifyCrl"`
+}
+
+type v2Pkcs12InfoParams struct {
+	P12        string `json:"p12"`
+	Password   string `json:"password"`
+	Alias      string `json:"alias,omitempty"`
+	VerifyOcsp bool   `json:"verifyOcsp"`
+	VerifyCrl  bool   `json:"verifyCrl"`
+}
+
+// v2CertificateResponse reuses the v1 certificate model, NCANode 2.x kept its shape unchanged.
+type v2CertificateResponse struct {
+	v2Response
+	Certificate v1Certificate `json:"certificate"`
+}
+
+type v2Pkcs12AliasesResponse struct {
+	v2Response
+	Aliases []struct {
+		Alias    string `json:"alias"`
+		KeyUsage string `json:"keyUsage"`
+	} `json:"aliases"`
+}
+
+func (h *NCANodeV2Handler) SignWithSecurityHeader(ctx context.Context, xml string, hashAlgorithm types.HashAlgorithm, opts ...SignOption) (result entities.Response, err error) {
+	o := signOptions(opts)
+	if err = unsupported(`XML.signWithSecurityHeader`, `v2`, feature{`TrimXml`, o.TrimXml}, feature{`WithTsp`, o.WithTsp}); err != nil {
+		return result, err
+	}
+	if o.HashAlgorithm != `` {
+		hashAlgorithm = o.HashAlgorithm
+	}
+	if err = checkHash(`XML.signWithSecurityHeader`, hashAlgorithm, types.NCAnodeV20); err != nil {
+		return result, err
+	}
+	ctx = o.context(ctx)
+
+	k, err := h.key(ctx, `XML.signWithSecurityHeader`)
+	if err != nil {
+		return result, err
+	}
+
+	p := v2XmlSignParams{
+		P12array:         []v2Key{k},
+		Xml:              xml,
+		TspHashAlgorithm: hashAlgorithm,
+	}
+
+	var respStruct v2XmlSignResponse
+	err = h.execute(ctx, `XML.signWithSecurityHeader`, p, &respStruct)
+	if err != nil {
+		return result, err
+	}
+
+	result.Result.Xml = respStruct.Xml
+	result.Result.Raw = respStruct.Xml
+	result.Status = respStruct.Status
+	result.Message = respStruct.Message
+
+	return result, nil
+}
+
+func (h *NCANodeV2Handler) VerifyXml(ctx context.Context, xml string, checks ...types.RevocationCheck) (result entities.VerifyResult, err error) {
+	ctx = api.WithIdempotent(ctx)
+
+	p := v2XmlVerifyParams{
+		Xml:        xml,
+		VerifyOcsp: hasRevocationCheck(checks, types.OCSP),
+		VerifyCrl:  hasRevocationCheck(checks, types.CRL),
+	}
+
+	var respStruct v2XmlVerifyResponse
+	err = h.execute(ctx, `XML.verify`, p, &respStruct)
+	if err != nil {
+		return result, err
+	}
+
+	result.Valid = respStruct.Valid
+	for _, s := range respStruct.Signers {
+		result.Signers = append(result.Signers, s.entity())
+	}
+
+	return result, nil
+}
+
+// VerifyWithSecurityHeader uses XML.verify as NCANode v2 has no dedicated WS-Security verification method.
+func (h *NCANodeV2Handler) VerifyWithSecurityHeader(ctx context.Context, xml string, checks ...types.RevocationCheck) (result entities.VerifyResult, err error) {
+	return h.VerifyXml(ctx, xml, checks...)
+}
+
+func (h *NCANodeV2Handler) SignCms(ctx context.Context, data io.Reader, detached bool, opts ...SignOption) (result entities.CmsResult, err error) {
+	b, err := readCmsData(`CMS.sign`, data)
+	if err != nil {
+		return result, err
+	}
+
+	return h.SignCmsBytes(ctx, b, detached, opts...)
+}
+
+func (h *NCANodeV2Handler) SignCmsBytes(ctx context.Context, data []byte, detached bool, opts ...SignOption) (result entities.CmsResult, err error) {
+	o := signOptions(opts)
+	if err = unsupported(`CMS.sign`, `v2`, feature{`TrimXml`, o.TrimXml}); err != nil {
+		return result, err
+	}
+	if err = checkHash(`CMS.sign`, o.HashAlgorithm, types.NCAnodeV20); err != nil {
+		return result, err
+	}
+	ctx = o.context(ctx)
+
+	k, err := h.key(ctx, `CMS.sign`)
+	if err != nil {
+		return result, err
+	}
+
+	p := v2CmsSignParams{
+		P12array: []v2Key{k},
+		Data:     base64.StdEncoding.EncodeToString(data),
+		Detached: detached,
+		WithTsp:  o.WithTsp,
+
+		TsaPolicy:        o.TsaPolicy,
+		TspHashAlgorithm: o.HashAlgorithm,
+	}
+
+	var respStruct v2CmsResponse
+	err = h.execute(ctx, `CMS.sign`, p, &respStruct)
+	if err != nil {
+		return result, err
+	}
+
+	return decodeCms(`CMS.sign`, respStruct.Cms)
+}
+
+func (h *NCANodeV2Handler) VerifyCms(ctx context.Context, cms []byte, data []byte, checks ...types.RevocationCheck) (result entities.CmsVerifyResult, err error) {
+	ctx = api.WithIdempotent(ctx)
+
+	p := v2CmsVerifyParams{
+		Cms:        base64.StdEncoding.EncodeToString(cms),
+		VerifyOcsp: hasRevocationCheck(checks, types.OCSP),
+		VerifyCrl:  hasRevocationCheck(checks, types.CRL),
+	}
+	if data != nil {
+		d := base64.StdEncoding.EncodeToString(data)
+		p.Data = &d
+	}
+
+	var respStruct v2CmsVerifyResponse
+	err = h.execute(ctx, `CMS.verify`, p, &respStruct)
+	if err != nil {
+		return result, err
+	}
+
+	result.Valid = respStruct.Valid
+	for _, s := range respStruct.Signers {
+		var chain []entities.Certificate
+		for _, c := range s.Chain {
+			chain = append(chain, c.entity())
+		}
+
+		var signingTime *time.Time
+		if s.Tsp != nil {
+			signingTime = s.Tsp.GenTime.ptr()
+		}
+
+		result.Signers = append(result.Signers, cmsSigner(chain, signingTime))
+	}
+
+	return result, nil
+}
+
+func (h *NCANodeV2Handler) ExtractCms(ctx context.Context, cms []byte) (data []byte, err error) {
+	ctx = api.WithIdempotent(ctx)
+
+	p := v2CmsExtractParams{
+		Cms: base64.StdEncoding.EncodeToString(cms),
+	}
+
+	var respStruct v2CmsExtractResponse
+	err = h.execute(ctx, `CMS.extract`, p, &respStruct)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeCmsData(`CMS.extract`, respStruct.Data)
+}
+
+func (h *NCANodeV2Handler) X509Info(ctx context.Context, cert []byte, checks ...types.RevocationCheck) (result entities.Certificate, err error) {
+	ctx = api.WithIdempotent(ctx)
+
+	p := v2X509InfoParams{
+		Cert:       base64.StdEncoding.EncodeToString(cert),
+		VerifyOcsp: hasRevocationCheck(checks, types.OCSP),
+		VerifyCrl:  hasRevocationCheck(checks, types.CRL),
+	}
+
+	var respStruct v2CertificateResponse
+	err = h.execute(ctx, `X509.info`, p, &respStruct)
+	if err != nil {
+		return result, err
+	}
+
+	return respStruct.Certificate.entity(), nil
+}
+
+func (h *NCANodeV2Handler) Pkcs12Info(ctx context.Context, checks ...types.RevocationCheck) (result entities.Certificate, err error) {
+	ctx = api.WithIdempotent(ctx)
+
+	k, err := h.key(ctx, `PKCS12.info`)
+	if err != nil {
+		return result, err
+	}
+
+	p := v2Pkcs12InfoParams{
+		P12:        k.P12,
+		Password:   k.Password,
+		Alias:      k.Alias,
+		VerifyOcsp: hasRevocationCheck(checks, types.OCSP),
+		VerifyCrl:  hasRevocationCheck(checks, types.CRL),
+	}
+
+	var respStruct v2CertificateResponse
+	err = h.execute(ctx, `PKCS12.info`, p, &respStruct)
+	if err != nil {
+		return result, err
+	}
+
+	return respStruct.Certificate.entity(), nil
+}
+
+func (h *NCANodeV2Handler) Pkcs12Aliases(ctx context.Context) (result []entities.KeyAlias, err error) {
+	ctx = api.WithIdempotent(ctx)
+
+	k, err := h.key(ctx, `PKCS12.aliases`)
+	if err != nil {
+		return nil, err
+	}
+	k.Alias = ``
+
+	var respStruct v2Pkcs12AliasesResponse
+	err = h.execute(ctx, `PKCS12.aliases`, k, &respStruct)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, a := range respStruct.Aliases {
+		result = append(result, entities.KeyAlias{
+			Alias:    a.Alias,
+			KeyUsage: a.KeyUsage,
+		})
+	}
+
+	return result, nil
+}
+
+func (h *NCANodeV2Handler) key(ctx context.Context, op string) (v2Key, error) {
+	start := time.Now()
+	k, err := resolveKey(ctx, op, h.Keys, h.P12base64, h.P12pass, h.KeyAlias)
+	if err != nil {
+		failCall(ctx, h.Logger, h.Tracer, h.Metrics, types.NCAnodeV20, op, ``, start, err)
+		return v2Key{}, err
+	}
+
+	return v2Key{
+		P12:      k.P12base64,
+		Password: k.Password,
+		Alias:    k.Alias,
+	}, nil
+}
+
+func (h *NCANodeV2Handler) execute(ctx context.Context, method string, params interface{}, resp v2StatusResponse) (err error) {
+	start := time.Now()
+	ctx, span := startSpan(ctx, h.Tracer, types.NCAnodeV20, method, ``)
+
+	ctx, cancel := context.WithTimeout(ctx, callTimeout(ctx, h.Timeout))
+	defer cancel()
+
+	var rs, b []byte
+	defer func() {
+		endSpan(span, start, resp.response().Status, err)
+		recordCall(h.Metrics, types.NCAnodeV20, method, ``, start, resp.response().Status, err)
+		logCall(ctx, h.Logger, types.NCAnodeV20, method, ``, start, resp.response().Status, rs, b, err)
+	}()
+
+	rs, err = json.Marshal(rpcRequest{Version: string(types.NCAnodeV20), Method: method, Params: params})
+	if err != nil {
+		return encodeError(method, err)
+	}
+
+	rb := bytes.NewBuffer(rs)
+
+	b, err = h.Api.Request(ctx, http.MethodPost, ``, rb)
+	if err != nil {
+		return requestError(method, err, b)
+	}
+
+	err = json.Unmarshal(b, resp)
+	if err != nil {
+		return &DecodeError{Op: method, What: `http response json`, Err: err}
+	}
+
+	if s := resp.response(); s.Status != v2StatusOK {
+		msg := s.Message
+		if msg == `` {
+			msg = `unknown error`
+		}
+		return &NodeError{Op: method, Status: s.Status, Message: msg}
+	}
+
+	return nil
+}
@@ -0,0 +1,236 @@
+package goncanode
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/nbah1990/goncanode/types"
+)
+
+type mockApiClientV2 struct {
+	response []byte
+	err      error
+
+	body []byte
+}
+
+func (m *mockApiClientV2) Request(_ context.Context, _ string, _ string, data *bytes.Buffer) ([]byte, error) {
+	m.body = data.Bytes()
+	return m.response, m.err
+}
+
+func TestNCANodeV2Handler_SignWithSecurityHeader(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		client := &mockApiClientV2{
+			response: []byte(`{"status":0,"message":"","xml":"<signedXml></signedXml>"}`),
+		}
+		handler := &NCANodeV2Handler{
+			P12base64: "base64string",
+			P12pass:   "password",
+			Api:       client,
+		}
+
+		ctx := context.Background()
+		result, err := handler.SignWithSecurityHeader(ctx, "<xml></xml>", types.GOST34311GT)
+		if err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+		if result.Result.Xml != "<signedXml></signedXml>" {
+			t.Errorf("Incorrect Xml result, got: %s", result.Result.Xml)
+		}
+		if !strings.Contains(string(client.body), `{"version":"2.0","method":"XML.signWithSecurityHeader","params":{"p12array":[{"p12":"base64string","password":"password"}]`) {
+			t.Errorf("Unexpected request body: %s", client.body)
+		}
+	})
+
+	t.Run("ApiRequestError", func(t *testing.T) {
+		handler := &NCANodeV2Handler{
+			P12base64: "base64string",
+			P12pass:   "password",
+			Api: &mockApiClientV2{
+				err: errors.New("request error"),
+			},
+		}
+
+		ctx := context.Background()
+		_, err := handler.SignWithSecurityHeader(ctx, "<xml></xml>", types.GOST34311GT)
+		if err == nil || err.Error() != "XML.signWithSecurityHeader: http request error: request error" {
+			t.Errorf("Expected API request error, got: %v", err)
+		}
+	})
+
+	t.Run("JsonUnmarshalError", func(t *testing.T) {
+		handler := &NCANodeV2Handler{
+			P12base64: "base64string",
+			P12pass:   "password",
+			Api: &mockApiClientV2{
+				response: []byte(`invalid json`),
+			},
+		}
+
+		ctx := context.Background()
+		_, err := handler.SignWithSecurityHeader(ctx, "<xml></xml>", types.GOST34311GT)
+		if err == nil {
+			t.Errorf("Expected JSON unmarshalling error, got nil")
+		}
+	})
+
+	t.Run("NonOKStatus", func(t *testing.T) {
+		handler := &NCANodeV2Handler{
+			P12base64: "base64string",
+			P12pass:   "password",
+			Api: &mockApiClientV2{
+				response: []byte(`{"status":-1,"message":"Invalid password"}`),
+			},
+		}
+
+		ctx := context.Background()
+		_, err := handler.SignWithSecurityHeader(ctx, "<xml></xml>", types.GOST34311GT)
+		if err == nil || err.Error() != "XML.signWithSecurityHeader: http error: Invalid password, status: -1" {
+			t.Errorf("Expected error with unsuccessful status, got: %v", err)
+		}
+	})
+
+	t.Run("NonOKStatusWithoutMessage", func(t *testing.T) {
+		handler := &NCANodeV2Handler{
+			Api: &mockApiClientV2{
+				response: []byte(`{"status":500}`),
+			},
+		}
+
+		ctx := context.Background()
+		_, err := handler.SignWithSecurityHeader(ctx, "<xml></xml>", types.GOST34311GT)
+		if err == nil || err.Error() != "XML.signWithSecurityHeader: http error: unknown error, status: 500" {
+			t.Errorf("Expected error with unsuccessful status, got: %v", err)
+		}
+	})
+}
+
+func TestNCANodeV2Handler_VerifyXml(t *testing.T) {
+	client := &mockApiClientV2{
+		response: []byte(`{"status":0,"message":"","valid":true,"signers":[{"valid":true,"serialNumber":"01","ocsp":{"status":"ACTIVE"}}]}`),
+	}
+	handler := &NCANodeV2Handler{Api: client}
+
+	result, err := handler.VerifyWithSecurityHeader(context.Background(), "<xml></xml>", types.OCSP)
+	if err != nil {
+		t.Fatalf("Expected no errors, got: %v", err)
+	}
+	if !strings.Contains(string(client.body), `"method":"XML.verify","params":{"xml":"\u003cxml\u003e\u003c/xml\u003e","verifyOcsp":true,"verifyCrl":false}`) {
+		t.Errorf("Unexpected request body: %s", client.body)
+	}
+	if !result.Valid || len(result.Signers) != 1 || len(result.Signers[0].Revocations) != 1 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+}
+
+func TestNCANodeV2Handler_Cms(t *testing.T) {
+	t.Run("Sign", func(t *testing.T) {
+		client := &mockApiClientV2{
+			response: []byte(`{"status":0,"message":"","cms":"MA=="}`),
+		}
+		handler := &NCANodeV2Handler{Api: client}
+
+		result, err := handler.SignCms(context.Background(), strings.NewReader("data"), true)
+		if err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+		if !strings.Contains(string(client.body), `"method":"CMS.sign"`) || !strings.Contains(string(client.body), `"data":"ZGF0YQ==","detached":true`) {
+			t.Errorf("Unexpected request body: %s", client.body)
+		}
+		if !bytes.Equal(result.Der, []byte{0x30}) {
+			t.Errorf("Incorrect cms result, got: %+v", result)
+		}
+	})
+
+	t.Run("Verify", func(t *testing.T) {
+		client := &mockApiClientV2{
+			response: []byte(`{"status":0,"message":"","valid":true,"signers":[{"chain":[{"valid":true,"serialNumber":"01"},{"valid":true,"serialNumber":"02"}],"tsp":{"genTime":"2023-05-01 10:00:00"}}]}`),
+		}
+		handler := &NCANodeV2Handler{Api: client}
+
+		result, err := handler.VerifyCms(context.Background(), []byte{0x30}, []byte("data"))
+		if err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+		if !strings.Contains(string(client.body), `"method":"CMS.verify","params":{"cms":"MA==","data":"ZGF0YQ=="`) {
+			t.Errorf("Unexpected request body: %s", client.body)
+		}
+		if len(result.Signers) != 1 || !result.Signers[0].ChainValid || result.Signers[0].SigningTime == nil {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+	})
+
+	t.Run("Extract", func(t *testing.T) {
+		handler := &NCANodeV2Handler{
+			Api: &mockApiClientV2{
+				response: []byte(`{"status":0,"message":"","data":"ZGF0YQ=="}`),
+			},
+		}
+
+		data, err := handler.ExtractCms(context.Background(), []byte{0x30})
+		if err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+		if string(data) != "data" {
+			t.Errorf("Incorrect data, got: %s", data)
+		}
+	})
+}
+
+func TestNCANodeV2Handler_CertificateInfo(t *testing.T) {
+	t.Run("X509Info", func(t *testing.T) {
+		client := &mockApiClientV2{
+			response: []byte(`{"status":0,"message":"","certificate":{"valid":true,"serialNumber":"01","subject":{"iin":"123456789012"}}}`),
+		}
+		handler := &NCANodeV2Handler{Api: client}
+
+		result, err := handler.X509Info(context.Background(), []byte{0x30})
+		if err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+		if !strings.Contains(string(client.body), `"method":"X509.info"`) {
+			t.Errorf("Unexpected request body: %s", client.body)
+		}
+		if result.Subject.IIN != "123456789012" {
+			t.Errorf("Incorrect certificate, got: %+v", result)
+		}
+	})
+
+	t.Run("Pkcs12Info", func(t *testing.T) {
+		client := &mockApiClientV2{
+			response: []byte(`{"status":0,"message":"","certificate":{"valid":true,"serialNumber":"01"}}`),
+		}
+		handler := &NCANodeV2Handler{P12base64: "base64string", P12pass: "password", Api: client}
+
+		result, err := handler.Pkcs12Info(context.Background())
+		if err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+		if !strings.Contains(string(client.body), `"method":"PKCS12.info","params":{"p12":"base64string","password":"password"`) {
+			t.Errorf("Unexpected request body: %s", client.body)
+		}
+		if result.SerialNumber != "01" {
+			t.Errorf("Incorrect certificate, got: %+v", result)
+		}
+	})
+
+	t.Run("Pkcs12Aliases", func(t *testing.T) {
+		handler := &NCANodeV2Handler{
+			Api: &mockApiClientV2{
+				response: []byte(`{"status":0,"message":"","aliases":[{"alias":"sign","keyUsage":"SIGN"}]}`),
+			},
+		}
+
+		result, err := handler.Pkcs12Aliases(context.Background())
+		if err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+		if len(result) != 1 || result[0].KeyUsage != "SIGN" {
+			t.Errorf("Incorrect aliases, got: %+v", result)
+		}
+	})
+}
@@ -3,12 +3,16 @@ package goncanode
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"github.com/nbah1990/goncanode/api"
 	"github.com/nbah1990/goncanode/entities"
+	"github.com/nbah1990/goncanode/metrics"
+	"github.com/nbah1990/goncanode/tracing"
 	"github.com/nbah1990/goncanode/types"
+	"io"
+	"log/slog"
 	"net/http"
 	"time"
 )
@@ -16,11 +20,36 @@ import (
 type NCANodeV3Handler struct {
 	P12base64 string
 	P12pass   string
-	Timeout   time.Duration
+	// Keys supplies the key per call instead of P12base64 and P12pass when set.
+	Keys entities.KeyProvider
+	// KeyAlias selects the key of a container holding several, SignKeyAlias overrides it per call.
+	KeyAlias string
+	Timeout  time.Duration
+	// Tsp timestamps every CMS signature, per call sign options take precedence.
+	Tsp *entities.Tsp
+	// Logger reports every call, nothing is logged when nil.
+	Logger *slog.Logger
+	// Tracer gets a span per call, nothing is traced when nil.
+	Tracer tracing.Tracer
+	// Metrics gets call counts and latencies, nothing is collected when nil.
+	Metrics metrics.Collector
 
 	Api api.IClient
 }
 
+type v3Response struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+func (r *v3Response) response() *v3Response {
+	return r
+}
+
+type v3StatusResponse interface {
+	response() *v3Response
+}
+
 type wsseSignRequest struct {
 	Xml      string  `json:"xml"`
 	Key      string  `json:"key"`
@@ -30,43 +59,218 @@ type wsseSignRequest struct {
 }
 
 type wsseSignResponse struct {
-	Status  int    `json:"status"`
-	Message string `json:"message"`
-	Xml     string `json:"xml"`
+	v3Response
+	Xml string `json:"xml"`
 }
 
-func (h *NCANodeV3Handler) SignWithSecurityHeader(ctx context.Context, xmlS string, _ types.HashAlgorithm) (result entities.Response, err error) {
-	ctx, cancel := context.WithTimeout(ctx, h.Timeout)
-	defer cancel()
+type v3Signer struct {
+	Key      string  `json:"key"`
+	Password string  `json:"password"`
+	KeyAlias *string `json:"keyAlias"`
+}
 
-	r := wsseSignRequest{
-		Xml:      xmlS,
-		Key:      h.P12base64,
-		Password: h.P12pass,
-		TrimXml:  false,
-		KeyAlias: nil,
+type v3CmsSignRequest struct {
+	Data     string     `json:"data"`
+	Signers  []v3Signer `json:"signers"`
+	WithTsp  bool       `json:"withTsp"`
+	Detached bool       `json:"detached"`
+
+	TsaPolicy types.TsaPolicy `json:"tsaPolicy,omitempty"`
+}
+
+type v3CmsSignAddRequest struct {
+	Cms      string     `json:"cms"`
+	Data     *string    `json:"data"`
+	Signers  []v3Signer `json:"signers"`
+	WithTsp  bool       `json:"withTsp"`
+	Detached bool       `json:"detached"`
+
+	TsaPolicy types.TsaPolicy `json:"tsaPolicy,omitempty"`
+}
+
+type v3Tsp struct {
+	WithTsp   bool
+	TsaPolicy types.TsaPolicy
+}
+
+// tsp merges the handler Tsp and the sign options into the timestamp settings of a call.
+// Timestamps are opt-in, the hash algorithm only selects the TSA policy of a requested one.
+func (h *NCANodeV3Handler) tsp(op string, hash types.HashAlgorithm, o SignOptions) (t v3Tsp, err error) {
+	if h.Tsp != nil {
+		t = v3Tsp{WithTsp: true, TsaPolicy: h.Tsp.Policy}
+	}
+	if o.WithTsp {
+		t.WithTsp = true
+	}
+	if o.TsaPolicy != `` {
+		t.TsaPolicy = o.TsaPolicy
 	}
 
-	rs, err := json.Marshal(r)
-	if err != nil {
-		return result, errors.New(fmt.Sprintf(`SignXml: can't encode request json: %s`, err))
+	if err = checkHash(op, hash, types.NCAnodeV30); err != nil || hash == `` {
+		return t, err
+	}
+	p := hash.TsaPolicy()
+	if o.TsaPolicy != `` && o.TsaPolicy != p {
+		return t, fmt.Errorf(`%s: hash algorithm %s conflicts with TSA policy %s`, op, hash, o.TsaPolicy)
+	}
+	if t.WithTsp {
+		t.TsaPolicy = p
 	}
 
-	rb := bytes.NewBuffer(rs)
+	return t, nil
+}
 
-	resp, err := h.Api.Request(ctx, http.MethodPost, `/wsse/sign`, rb)
-	if err != nil {
-		return result, errors.New(fmt.Sprintf(`SignXml: http request error: %s`, err))
+type v3CmsResponse struct {
+	v3Response
+	Cms string `json:"cms"`
+}
+
+type v3CmsVerifyRequest struct {
+	Cms             string                  `json:"cms"`
+	Data            *string                 `json:"data"`
+	RevocationCheck []types.RevocationCheck `json:"revocationCheck"`
+}
+
+type v3CmsVerifyResponse struct {
+	v3Response
+	Valid   bool `json:"valid"`
+	Signers []struct {
+		Certificates []v3Certificate `json:"certificates"`
+		SigningTime  *nodeTime       `json:"signingTime"`
+		Tsp          *struct {
+			GenTime *nodeTime `json:"genTime"`
+		} `json:"tsp"`
+	} `json:"signers"`
+}
+
+type v3CmsExtractRequest struct {
+	Cms string `json:"cms"`
+}
+
+type v3CmsExtractResponse struct {
+	v3Response
+	Data string `json:"data"`
+}
+
+type v3X509InfoRequest struct {
+	Certs           []string                `json:"certs"`
+	RevocationCheck []types.RevocationCheck `json:"revocationCheck"`
+}
+
+type v3Pkcs12InfoRequest struct {
+	Keys            []v3Signer              `json:"keys"`
+	RevocationCheck []types.RevocationCheck `json:"revocationCheck"`
+}
+
+type v3CertificatesResponse struct {
+	v3Response
+	Signers []v3Certificate `json:"signers"`
+}
+
+type v3Pkcs12AliasesRequest struct {
+	Keys []v3Signer `json:"keys"`
+}
+
+type v3Pkcs12AliasesResponse struct {
+	v3Response
+	Aliases []struct {
+		Alias    string `json:"alias"`
+		KeyUsage string `json:"keyUsage"`
+	} `json:"aliases"`
+}
+
+type v3XmlVerifyRequest struct {
+	Xml             string                  `json:"xml"`
+	RevocationCheck []types.RevocationCheck `json:"revocationCheck"`
+}
+
+type v3XmlVerifyResponse struct {
+	v3Response
+	Valid   bool            `json:"valid"`
+	Signers []v3Certificate `json:"signers"`
+}
+
+type v3Certificate struct {
+	Valid        bool           `json:"valid"`
+	Revocations  []v3Revocation `json:"revocations"`
+	NotBefore    nodeTime       `json:"notBefore"`
+	NotAfter     nodeTime       `json:"notAfter"`
+	KeyUsage     string         `json:"keyUsage"`
+	SerialNumber string         `json:"serialNumber"`
+	SignAlg      string         `json:"signAlg"`
+	Policies     []string       `json:"policies"`
+	KeyUser      []string       `json:"keyUser"`
+	Subject      nodeSubject    `json:"subject"`
+	Issuer       nodeSubject    `json:"issuer"`
+	Certificate  string         `json:"certificate"`
+}
+
+type v3Revocation struct {
+	Revoked        bool                  `json:"revoked"`
+	By             types.RevocationCheck `json:"by"`
+	RevocationTime *nodeTime             `json:"revocationTime"`
+	Reason         string                `json:"reason"`
+}
+
+func (c v3Certificate) entity() entities.Certificate {
+	r := entities.Certificate{
+		Valid:        c.Valid,
+		SerialNumber: c.SerialNumber,
+		NotBefore:    c.NotBefore.Time,
+		NotAfter:     c.NotAfter.Time,
+		KeyUsage:     c.KeyUsage,
+		KeyUser:      c.KeyUser,
+		SignAlg:      c.SignAlg,
+		Policies:     c.Policies,
+		Subject:      c.Subject.entity(),
+		Issuer:       c.Issuer.entity(),
+		Certificate:  c.Certificate,
 	}
 
-	var respStruct wsseSignResponse
-	err = json.Unmarshal(resp, &respStruct)
+	for _, rv := range c.Revocations {
+		r.Revocations = append(r.Revocations, entities.Revocation{
+			By:             rv.By,
+			Revoked:        rv.Revoked,
+			RevocationTime: rv.RevocationTime.ptr(),
+			Reason:         rv.Reason,
+		})
+	}
+
+	return r
+}
+
+// SignWithSecurityHeader never timestamps, NCANode v3 has no TSP settings for WS-Security signatures.
+// Any hashAlgorithm is rejected with a *HashAlgorithmError instead of being ignored, pass an empty one.
+func (h *NCANodeV3Handler) SignWithSecurityHeader(ctx context.Context, xmlS string, hashAlgorithm types.HashAlgorithm, opts ...SignOption) (result entities.Response, err error) {
+	o := signOptions(opts)
+	if err = unsupported(`SignXml`, `v3`, feature{`WithTsp`, o.WithTsp}); err != nil {
+		return result, err
+	}
+	if o.HashAlgorithm != `` {
+		hashAlgorithm = o.HashAlgorithm
+	}
+	if hashAlgorithm != `` {
+		return result, &HashAlgorithmError{Op: `SignXml`, Algorithm: hashAlgorithm, Version: types.NCAnodeV30}
+	}
+	ctx = o.context(ctx)
+
+	k, err := h.signer(ctx, `SignXml`, `/wsse/sign`)
 	if err != nil {
-		return result, errors.New(fmt.Sprintf(`SignXml: can't decode http response json: %s`, err))
+		return result, err
 	}
 
-	if respStruct.Status != http.StatusOK {
-		return result, errors.New(fmt.Sprintf(`SignXml: http error: %s, status: %d`, respStruct.Message, respStruct.Status))
+	r := wsseSignRequest{
+		Xml:      xmlS,
+		Key:      k.Key,
+		Password: k.Password,
+		TrimXml:  o.TrimXml,
+		KeyAlias: k.KeyAlias,
+	}
+
+	var respStruct wsseSignResponse
+	err = h.execute(ctx, `SignXml`, `/wsse/sign`, r, &respStruct)
+	if err != nil {
+		return result, err
 	}
 
 	result.Result.Xml = respStruct.Xml
@@ -76,3 +280,292 @@ func (h *NCANodeV3Handler) SignWithSecurityHeader(ctx context.Context, xmlS stri
 
 	return result, nil
 }
+
+func (h *NCANodeV3Handler) SignCms(ctx context.Context, data io.Reader, detached bool, opts ...SignOption) (result entities.CmsResult, err error) {
+	b, err := readCmsData(`SignCms`, data)
+	if err != nil {
+		return result, err
+	}
+
+	return h.SignCmsBytes(ctx, b, detached, opts...)
+}
+
+func (h *NCANodeV3Handler) SignCmsBytes(ctx context.Context, data []byte, detached bool, opts ...SignOption) (result entities.CmsResult, err error) {
+	o := signOptions(opts)
+	if err = unsupported(`SignCms`, `v3`, feature{`TrimXml`, o.TrimXml}); err != nil {
+		return result, err
+	}
+	t, err := h.tsp(`SignCms`, o.HashAlgorithm, o)
+	if err != nil {
+		return result, err
+	}
+	ctx = o.context(ctx)
+
+	k, err := h.signer(ctx, `SignCms`, `/cms/sign`)
+	if err != nil {
+		return result, err
+	}
+
+	r := v3CmsSignRequest{
+		Data:     base64.StdEncoding.EncodeToString(data),
+		Signers:  []v3Signer{k},
+		WithTsp:  t.WithTsp,
+		Detached: detached,
+
+		TsaPolicy: t.TsaPolicy,
+	}
+
+	var respStruct v3CmsResponse
+	err = h.execute(ctx, `SignCms`, `/cms/sign`, r, &respStruct)
+	if err != nil {
+		return result, err
+	}
+
+	return decodeCms(`SignCms`, respStruct.Cms)
+}
+
+// AddCmsSignature co-signs an existing CMS with the configured key. Data must be passed for detached CMS only.
+func (h *NCANodeV3Handler) AddCmsSignature(ctx context.Context, cms []byte, data []byte, opts ...SignOption) (result entities.CmsResult, err error) {
+	o := signOptions(opts)
+	if err = unsupported(`AddCmsSignature`, `v3`, feature{`TrimXml`, o.TrimXml}); err != nil {
+		return result, err
+	}
+	t, err := h.tsp(`AddCmsSignature`, o.HashAlgorithm, o)
+	if err != nil {
+		return result, err
+	}
+	ctx = o.context(ctx)
+
+	k, err := h.signer(ctx, `AddCmsSignature`, `/cms/sign/add`)
+	if err != nil {
+		return result, err
+	}
+
+	r := v3CmsSignAddRequest{
+		Cms:      base64.StdEncoding.EncodeToString(cms),
+		Signers:  []v3Signer{k},
+		WithTsp:  t.WithTsp,
+		Detached: data != nil,
+
+		TsaPolicy: t.TsaPolicy,
+	}
+	if data != nil {
+		d := base64.StdEncoding.EncodeToString(data)
+		r.Data = &d
+	}
+
+	var respStruct v3CmsResponse
+	err = h.execute(ctx, `AddCmsSignature`, `/cms/sign/add`, r, &respStruct)
+	if err != nil {
+		return result, err
+	}
+
+	return decodeCms(`AddCmsSignature`, respStruct.Cms)
+}
+
+func (h *NCANodeV3Handler) VerifyCms(ctx context.Context, cms []byte, data []byte, checks ...types.RevocationCheck) (result entities.CmsVerifyResult, err error) {
+	ctx = api.WithIdempotent(ctx)
+
+	r := v3CmsVerifyRequest{
+		Cms:             base64.StdEncoding.EncodeToString(cms),
+		RevocationCheck: checks,
+	}
+	if data != nil {
+		d := base64.StdEncoding.EncodeToString(data)
+		r.Data = &d
+	}
+
+	var respStruct v3CmsVerifyResponse
+	err = h.execute(ctx, `VerifyCms`, `/cms/verify`, r, &respStruct)
+	if err != nil {
+		return result, err
+	}
+
+	result.Valid = respStruct.Valid
+	for _, s := range respStruct.Signers {
+		var chain []entities.Certificate
+		for _, c := range s.Certificates {
+			chain = append(chain, c.entity())
+		}
+
+		signingTime := s.SigningTime.ptr()
+		if signingTime == nil && s.Tsp != nil {
+			signingTime = s.Tsp.GenTime.ptr()
+		}
+
+		result.Signers = append(result.Signers, cmsSigner(chain, signingTime))
+	}
+
+	return result, nil
+}
+
+func (h *NCANodeV3Handler) ExtractCms(ctx context.Context, cms []byte) (data []byte, err error) {
+	ctx = api.WithIdempotent(ctx)
+
+	r := v3CmsExtractRequest{
+		Cms: base64.StdEncoding.EncodeToString(cms),
+	}
+
+	var respStruct v3CmsExtractResponse
+	err = h.execute(ctx, `ExtractCms`, `/cms/extract`, r, &respStruct)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeCmsData(`ExtractCms`, respStruct.Data)
+}
+
+func (h *NCANodeV3Handler) VerifyXml(ctx context.Context, xmlS string, checks ...types.RevocationCheck) (result entities.VerifyResult, err error) {
+	return h.verifyXml(ctx, `VerifyXml`, `/xml/verify`, xmlS, checks)
+}
+
+func (h *NCANodeV3Handler) VerifyWithSecurityHeader(ctx context.Context, xmlS string, checks ...types.RevocationCheck) (result entities.VerifyResult, err error) {
+	return h.verifyXml(ctx, `VerifyWithSecurityHeader`, `/wsse/verify`, xmlS, checks)
+}
+
+func (h *NCANodeV3Handler) verifyXml(ctx context.Context, op string, url string, xmlS string, checks []types.RevocationCheck) (result entities.VerifyResult, err error) {
+	ctx = api.WithIdempotent(ctx)
+
+	r := v3XmlVerifyRequest{
+		Xml:             xmlS,
+		RevocationCheck: checks,
+	}
+
+	var respStruct v3XmlVerifyResponse
+	err = h.execute(ctx, op, url, r, &respStruct)
+	if err != nil {
+		return result, err
+	}
+
+	result.Valid = respStruct.Valid
+	for _, s := range respStruct.Signers {
+		result.Signers = append(result.Signers, s.entity())
+	}
+
+	return result, nil
+}
+
+func (h *NCANodeV3Handler) X509Info(ctx context.Context, cert []byte, checks ...types.RevocationCheck) (result entities.Certificate, err error) {
+	r := v3X509InfoRequest{
+		Certs:           []string{base64.StdEncoding.EncodeToString(cert)},
+		RevocationCheck: checks,
+	}
+
+	return h.certificateInfo(ctx, `X509Info`, `/x509/info`, r)
+}
+
+func (h *NCANodeV3Handler) Pkcs12Info(ctx context.Context, checks ...types.RevocationCheck) (result entities.Certificate, err error) {
+	k, err := h.signer(ctx, `Pkcs12Info`, `/pkcs12/info`)
+	if err != nil {
+		return result, err
+	}
+
+	r := v3Pkcs12InfoRequest{
+		Keys:            []v3Signer{k},
+		RevocationCheck: checks,
+	}
+
+	return h.certificateInfo(ctx, `Pkcs12Info`, `/pkcs12/info`, r)
+}
+
+func (h *NCANodeV3Handler) certificateInfo(ctx context.Context, op string, url string, r interface{}) (result entities.Certificate, err error) {
+	ctx = api.WithIdempotent(ctx)
+
+	var respStruct v3CertificatesResponse
+	err = h.execute(ctx, op, url, r, &respStruct)
+	if err != nil {
+		return result, err
+	}
+
+	if len(respStruct.Signers) == 0 {
+		return result, &DecodeError{Op: op, What: `http response`, Err: errEmptyCertificates}
+	}
+
+	return respStruct.Signers[0].entity(), nil
+}
+
+func (h *NCANodeV3Handler) Pkcs12Aliases(ctx context.Context) (result []entities.KeyAlias, err error) {
+	ctx = api.WithIdempotent(ctx)
+
+	k, err := h.signer(ctx, `Pkcs12Aliases`, `/pkcs12/aliases`)
+	if err != nil {
+		return nil, err
+	}
+	k.KeyAlias = nil
+
+	r := v3Pkcs12AliasesRequest{
+		Keys: []v3Signer{k},
+	}
+
+	var respStruct v3Pkcs12AliasesResponse
+	err = h.execute(ctx, `Pkcs12Aliases`, `/pkcs12/aliases`, r, &respStruct)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, a := range respStruct.Aliases {
+		result = append(result, entities.KeyAlias{
+			Alias:    a.Alias,
+			KeyUsage: a.KeyUsage,
+		})
+	}
+
+	return result, nil
+}
+
+func (h *NCANodeV3Handler) signer(ctx context.Context, op string, url string) (v3Signer, error) {
+	start := time.Now()
+	k, err := resolveKey(ctx, op, h.Keys, h.P12base64, h.P12pass, h.KeyAlias)
+	if err != nil {
+		failCall(ctx, h.Logger, h.Tracer, h.Metrics, types.NCAnodeV30, op, url, start, err)
+		return v3Signer{}, err
+	}
+
+	s := v3Signer{
+		Key:      k.P12base64,
+		Password: k.Password,
+	}
+	if k.Alias != `` {
+		s.KeyAlias = &k.Alias
+	}
+
+	return s, nil
+}
+
+func (h *NCANodeV3Handler) execute(ctx context.Context, op string, url string, r interface{}, resp v3StatusResponse) (err error) {
+	start := time.Now()
+	ctx, span := startSpan(ctx, h.Tracer, types.NCAnodeV30, op, url)
+
+	ctx, cancel := context.WithTimeout(ctx, callTimeout(ctx, h.Timeout))
+	defer cancel()
+
+	var rs, b []byte
+	defer func() {
+		endSpan(span, start, resp.response().Status, err)
+		recordCall(h.Metrics, types.NCAnodeV30, op, url, start, resp.response().Status, err)
+		logCall(ctx, h.Logger, types.NCAnodeV30, op, url, start, resp.response().Status, rs, b, err)
+	}()
+
+	rs, err = json.Marshal(r)
+	if err != nil {
+		return encodeError(op, err)
+	}
+
+	rb := bytes.NewBuffer(rs)
+
+	b, err = h.Api.Request(ctx, http.MethodPost, url, rb)
+	if err != nil {
+		return requestError(op, err, b)
+	}
+
+	err = json.Unmarshal(b, resp)
+	if err != nil {
+		return &DecodeError{Op: op, What: `http response json`, Err: err}
+	}
+
+	if s := resp.response(); s.Status != http.StatusOK {
+		return &NodeError{Op: op, Status: s.Status, Message: s.Message}
+	}
+
+	return nil
+}
@@ -4,15 +4,28 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"github.com/nbah1990/goncanode/api"
+	"github.com/nbah1990/goncanode/entities"
+	"github.com/nbah1990/goncanode/keys"
+	"github.com/nbah1990/goncanode/types"
+	"strings"
 	"testing"
+	"time"
 )
 
 type mockApiClient struct {
 	response []byte
 	err      error
+
+	url        string
+	body       []byte
+	idempotent bool
 }
 
-func (m *mockApiClient) Request(_ context.Context, _ string, _ string, _ *bytes.Buffer) ([]byte, error) {
+func (m *mockApiClient) Request(ctx context.Context, _ string, url string, data *bytes.Buffer) ([]byte, error) {
+	m.url = url
+	m.body = data.Bytes()
+	m.idempotent = api.IsIdempotent(ctx)
 	return m.response, m.err
 }
 
@@ -98,5 +111,533 @@ func TestSignXml(t *testing.T) {
 		if err == nil || err.Error() != "SignXml: http error: Bad Request, status: 400" {
 			t.Errorf("Expected error with unsuccessful status, got: %v", err)
 		}
+		var nodeErr *NodeError
+		if !errors.As(err, &nodeErr) || nodeErr.Status != 400 {
+			t.Errorf("Expected *NodeError with status 400, got: %v", err)
+		}
+	})
+
+	t.Run("RevokedCertificate", func(t *testing.T) {
+		handler := &NCANodeV3Handler{
+			Api: &mockApiClient{
+				response: []byte(`{"status":400,"message":"Certificate is revoked"}`),
+			},
+		}
+
+		_, err := handler.SignWithSecurityHeader(context.Background(), "<xml></xml>", ``)
+		if !errors.Is(err, ErrRevokedCertificate) {
+			t.Errorf("Expected ErrRevokedCertificate, got: %v", err)
+		}
+	})
+
+	t.Run("ErrorTypes", func(t *testing.T) {
+		handler := &NCANodeV3Handler{
+			Api: &mockApiClient{
+				response: []byte(`invalid json`),
+			},
+		}
+
+		_, err := handler.SignWithSecurityHeader(context.Background(), "<xml></xml>", ``)
+		var decodeErr *DecodeError
+		if !errors.As(err, &decodeErr) || decodeErr.Op != "SignXml" {
+			t.Errorf("Expected *DecodeError, got: %v", err)
+		}
+	})
+}
+
+func TestNCANodeV3Handler_VerifyXml(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		client := &mockApiClient{
+			response: []byte(`{"status":200,"message":"OK","valid":true,"signers":[{"valid":true,"notBefore":"2023-01-10T09:00:00.000+00:00","notAfter":"2024-01-10T09:00:00.000+00:00","serialNumber":"1a2b","keyUsage":"SIGN","subject":{"commonName":"TEST","iin":"123456789012"},"revocations":[{"revoked":false,"by":"OCSP"}]}]}`),
+		}
+		handler := &NCANodeV3Handler{Api: client}
+
+		result, err := handler.VerifyXml(context.Background(), "<xml></xml>", types.OCSP)
+		if err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+		if client.url != "/xml/verify" {
+			t.Errorf("Expected url /xml/verify, got: %s", client.url)
+		}
+		if !client.idempotent {
+			t.Errorf("Expected verification to be marked idempotent")
+		}
+		if !strings.Contains(string(client.body), `"revocationCheck":["OCSP"]`) {
+			t.Errorf("Expected OCSP revocation check in request, got: %s", client.body)
+		}
+		if !result.Valid || len(result.Signers) != 1 {
+			t.Fatalf("Unexpected result: %+v", result)
+		}
+		s := result.Signers[0]
+		if s.Subject.IIN != "123456789012" || s.SerialNumber != "1a2b" {
+			t.Errorf("Incorrect signer, got: %+v", s)
+		}
+		if s.NotAfter.Year() != 2024 {
+			t.Errorf("Incorrect notAfter, got: %v", s.NotAfter)
+		}
+		if len(s.Revocations) != 1 || s.Revocations[0].By != types.OCSP || s.Revocations[0].Revoked {
+			t.Errorf("Incorrect revocations, got: %+v", s.Revocations)
+		}
+	})
+
+	t.Run("WithSecurityHeader", func(t *testing.T) {
+		client := &mockApiClient{
+			response: []byte(`{"status":200,"message":"OK","valid":false,"signers":[]}`),
+		}
+		handler := &NCANodeV3Handler{Api: client}
+
+		result, err := handler.VerifyWithSecurityHeader(context.Background(), "<xml></xml>")
+		if err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+		if client.url != "/wsse/verify" {
+			t.Errorf("Expected url /wsse/verify, got: %s", client.url)
+		}
+		if result.Valid {
+			t.Errorf("Expected invalid result")
+		}
+	})
+
+	t.Run("NonOKStatus", func(t *testing.T) {
+		handler := &NCANodeV3Handler{
+			Api: &mockApiClient{
+				response: []byte(`{"status":400,"message":"Bad Request"}`),
+			},
+		}
+
+		_, err := handler.VerifyXml(context.Background(), "<xml></xml>")
+		if err == nil || err.Error() != "VerifyXml: http error: Bad Request, status: 400" {
+			t.Errorf("Expected error with unsuccessful status, got: %v", err)
+		}
+	})
+}
+
+func TestNCANodeV3Handler_SignCms(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		client := &mockApiClient{
+			response: []byte(`{"status":200,"message":"OK","cms":"MIIBAg=="}`),
+		}
+		handler := &NCANodeV3Handler{P12base64: "base64string", P12pass: "password", Api: client}
+
+		result, err := handler.SignCms(context.Background(), strings.NewReader(`{"a":1}`), true)
+		if err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+		if client.url != "/cms/sign" {
+			t.Errorf("Expected url /cms/sign, got: %s", client.url)
+		}
+		if client.idempotent {
+			t.Errorf("Expected signing not to be marked idempotent")
+		}
+		if !strings.Contains(string(client.body), `"data":"eyJhIjoxfQ=="`) || !strings.Contains(string(client.body), `"detached":true`) {
+			t.Errorf("Unexpected request body: %s", client.body)
+		}
+		if !strings.Contains(string(client.body), `"signers":[{"key":"base64string","password":"password","keyAlias":null}]`) {
+			t.Errorf("Expected configured key in request, got: %s", client.body)
+		}
+		if result.Base64 != "MIIBAg==" || !bytes.Equal(result.Der, []byte{0x30, 0x82, 0x01, 0x02}) {
+			t.Errorf("Incorrect cms result, got: %+v", result)
+		}
+	})
+
+	t.Run("InvalidBase64", func(t *testing.T) {
+		handler := &NCANodeV3Handler{
+			Api: &mockApiClient{
+				response: []byte(`{"status":200,"message":"OK","cms":"not base64"}`),
+			},
+		}
+
+		_, err := handler.SignCmsBytes(context.Background(), []byte("data"), false)
+		if err == nil || !strings.HasPrefix(err.Error(), "SignCms: can't decode cms base64") {
+			t.Errorf("Expected base64 decode error, got: %v", err)
+		}
+	})
+
+	t.Run("NonOKStatus", func(t *testing.T) {
+		handler := &NCANodeV3Handler{
+			Api: &mockApiClient{
+				response: []byte(`{"status":500,"message":"Internal Server Error"}`),
+			},
+		}
+
+		_, err := handler.SignCmsBytes(context.Background(), []byte("data"), false)
+		if err == nil || err.Error() != "SignCms: http error: Internal Server Error, status: 500" {
+			t.Errorf("Expected error with unsuccessful status, got: %v", err)
+		}
+	})
+}
+
+func TestNCANodeV3Handler_VerifyCms(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		client := &mockApiClient{
+			response: []byte(`{"status":200,"message":"OK","valid":true,"signers":[{"certificates":[{"valid":true,"serialNumber":"01","subject":{"iin":"123456789012"},"revocations":[{"revoked":false,"by":"CRL"}]},{"valid":false,"serialNumber":"02"}],"tsp":{"genTime":"2023-05-01T10:00:00Z"}}]}`),
+		}
+		handler := &NCANodeV3Handler{Api: client}
+
+		result, err := handler.VerifyCms(context.Background(), []byte{0x30}, []byte("data"), types.CRL)
+		if err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+		if client.url != "/cms/verify" {
+			t.Errorf("Expected url /cms/verify, got: %s", client.url)
+		}
+		if !strings.Contains(string(client.body), `"cms":"MA==","data":"ZGF0YQ==","revocationCheck":["CRL"]`) {
+			t.Errorf("Unexpected request body: %s", client.body)
+		}
+		if !result.Valid || len(result.Signers) != 1 {
+			t.Fatalf("Unexpected result: %+v", result)
+		}
+		s := result.Signers[0]
+		if s.Certificate.SerialNumber != "01" || len(s.Chain) != 1 || s.Chain[0].SerialNumber != "02" {
+			t.Errorf("Incorrect signer chain, got: %+v", s)
+		}
+		if s.ChainValid {
+			t.Errorf("Expected invalid chain")
+		}
+		if s.SigningTime == nil || s.SigningTime.Month() != 5 {
+			t.Errorf("Incorrect signing time, got: %v", s.SigningTime)
+		}
+	})
+
+	t.Run("Attached", func(t *testing.T) {
+		client := &mockApiClient{
+			response: []byte(`{"status":200,"message":"OK","valid":true,"signers":[]}`),
+		}
+		handler := &NCANodeV3Handler{Api: client}
+
+		_, err := handler.VerifyCms(context.Background(), []byte{0x30}, nil)
+		if err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+		if !strings.Contains(string(client.body), `"data":null`) {
+			t.Errorf("Expected no data in request, got: %s", client.body)
+		}
+	})
+}
+
+func TestNCANodeV3Handler_ExtractCms(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		client := &mockApiClient{
+			response: []byte(`{"status":200,"message":"OK","data":"ZGF0YQ=="}`),
+		}
+		handler := &NCANodeV3Handler{Api: client}
+
+		data, err := handler.ExtractCms(context.Background(), []byte{0x30})
+		if err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+		if client.url != "/cms/extract" {
+			t.Errorf("Expected url /cms/extract, got: %s", client.url)
+		}
+		if string(data) != "data" {
+			t.Errorf("Incorrect data, got: %s", data)
+		}
+	})
+
+	t.Run("NonOKStatus", func(t *testing.T) {
+		handler := &NCANodeV3Handler{
+			Api: &mockApiClient{
+				response: []byte(`{"status":400,"message":"Bad Request"}`),
+			},
+		}
+
+		_, err := handler.ExtractCms(context.Background(), []byte{0x30})
+		if err == nil || err.Error() != "ExtractCms: http error: Bad Request, status: 400" {
+			t.Errorf("Expected error with unsuccessful status, got: %v", err)
+		}
+	})
+}
+
+func TestNCANodeV3Handler_AddCmsSignature(t *testing.T) {
+	t.Run("Attached", func(t *testing.T) {
+		client := &mockApiClient{
+			response: []byte(`{"status":200,"message":"OK","cms":"MIIBAg=="}`),
+		}
+		handler := &NCANodeV3Handler{P12base64: "base64string", P12pass: "password", Api: client}
+
+		result, err := handler.AddCmsSignature(context.Background(), []byte{0x30}, nil)
+		if err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+		if client.url != "/cms/sign/add" {
+			t.Errorf("Expected url /cms/sign/add, got: %s", client.url)
+		}
+		if !strings.Contains(string(client.body), `"cms":"MA==","data":null,"signers":[{"key":"base64string","password":"password","keyAlias":null}]`) {
+			t.Errorf("Unexpected request body: %s", client.body)
+		}
+		if !strings.Contains(string(client.body), `"detached":false`) {
+			t.Errorf("Expected attached mode, got: %s", client.body)
+		}
+		if result.Base64 != "MIIBAg==" {
+			t.Errorf("Incorrect cms result, got: %+v", result)
+		}
+	})
+
+	t.Run("Detached", func(t *testing.T) {
+		client := &mockApiClient{
+			response: []byte(`{"status":200,"message":"OK","cms":"MIIBAg=="}`),
+		}
+		handler := &NCANodeV3Handler{Api: client}
+
+		_, err := handler.AddCmsSignature(context.Background(), []byte{0x30}, []byte("data"))
+		if err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+		if !strings.Contains(string(client.body), `"data":"ZGF0YQ=="`) || !strings.Contains(string(client.body), `"detached":true`) {
+			t.Errorf("Expected detached data in request, got: %s", client.body)
+		}
+	})
+
+	t.Run("ApiRequestError", func(t *testing.T) {
+		handler := &NCANodeV3Handler{
+			Api: &mockApiClient{
+				err: errors.New("request error"),
+			},
+		}
+
+		_, err := handler.AddCmsSignature(context.Background(), []byte{0x30}, nil)
+		if err == nil || err.Error() != "AddCmsSignature: http request error: request error" {
+			t.Errorf("Expected API request error, got: %v", err)
+		}
+	})
+
+	t.Run("NonOKStatus", func(t *testing.T) {
+		handler := &NCANodeV3Handler{
+			Api: &mockApiClient{
+				response: []byte(`{"status":400,"message":"Bad Request"}`),
+			},
+		}
+
+		_, err := handler.AddCmsSignature(context.Background(), []byte{0x30}, nil)
+		if err == nil || err.Error() != "AddCmsSignature: http error: Bad Request, status: 400" {
+			t.Errorf("Expected error with unsuccessful status, got: %v", err)
+		}
+	})
+}
+
+func TestNCANodeV3Handler_CertificateInfo(t *testing.T) {
+	t.Run("X509Info", func(t *testing.T) {
+		client := &mockApiClient{
+			response: []byte(`{"status":200,"message":"OK","signers":[{"valid":true,"serialNumber":"01","keyUsage":"SIGN","policies":["1.2.398.3.3.2.1"],"subject":{"commonName":"TEST","bin":"123456789012"},"issuer":{"commonName":"NCA"},"notBefore":"2023-01-10T09:00:00Z","notAfter":"2024-01-10T09:00:00Z"}]}`),
+		}
+		handler := &NCANodeV3Handler{Api: client}
+
+		result, err := handler.X509Info(context.Background(), []byte{0x30}, types.OCSP)
+		if err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+		if client.url != "/x509/info" {
+			t.Errorf("Expected url /x509/info, got: %s", client.url)
+		}
+		if !strings.Contains(string(client.body), `"certs":["MA=="],"revocationCheck":["OCSP"]`) {
+			t.Errorf("Unexpected request body: %s", client.body)
+		}
+		if result.Subject.BIN != "123456789012" || result.Issuer.CommonName != "NCA" || result.KeyUsage != "SIGN" {
+			t.Errorf("Incorrect certificate, got: %+v", result)
+		}
+		if len(result.Policies) != 1 || result.NotBefore.IsZero() {
+			t.Errorf("Incorrect certificate, got: %+v", result)
+		}
+	})
+
+	t.Run("Pkcs12Info", func(t *testing.T) {
+		client := &mockApiClient{
+			response: []byte(`{"status":200,"message":"OK","signers":[{"valid":true,"serialNumber":"01"}]}`),
+		}
+		handler := &NCANodeV3Handler{P12base64: "base64string", P12pass: "password", Api: client}
+
+		result, err := handler.Pkcs12Info(context.Background())
+		if err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+		if client.url != "/pkcs12/info" {
+			t.Errorf("Expected url /pkcs12/info, got: %s", client.url)
+		}
+		if !strings.Contains(string(client.body), `"keys":[{"key":"base64string","password":"password","keyAlias":null}]`) {
+			t.Errorf("Unexpected request body: %s", client.body)
+		}
+		if result.SerialNumber != "01" {
+			t.Errorf("Incorrect certificate, got: %+v", result)
+		}
+	})
+
+	t.Run("EmptyResponse", func(t *testing.T) {
+		handler := &NCANodeV3Handler{
+			Api: &mockApiClient{
+				response: []byte(`{"status":200,"message":"OK","signers":[]}`),
+			},
+		}
+
+		_, err := handler.Pkcs12Info(context.Background())
+		var decodeErr *DecodeError
+		if !errors.As(err, &decodeErr) || err.Error() != "Pkcs12Info: can't decode http response: empty certificate list" {
+			t.Errorf("Expected empty response error, got: %v", err)
+		}
+	})
+
+	t.Run("Pkcs12Aliases", func(t *testing.T) {
+		client := &mockApiClient{
+			response: []byte(`{"status":200,"message":"OK","aliases":[{"alias":"auth","keyUsage":"AUTH"},{"alias":"sign","keyUsage":"SIGN"}]}`),
+		}
+		handler := &NCANodeV3Handler{Api: client}
+
+		result, err := handler.Pkcs12Aliases(context.Background())
+		if err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+		if client.url != "/pkcs12/aliases" {
+			t.Errorf("Expected url /pkcs12/aliases, got: %s", client.url)
+		}
+		if len(result) != 2 || result[1].Alias != "sign" || result[1].KeyUsage != "SIGN" {
+			t.Errorf("Incorrect aliases, got: %+v", result)
+		}
+	})
+}
+
+func TestNCANodeV3Handler_HTTPError(t *testing.T) {
+	t.Run("NCANodeJsonBody", func(t *testing.T) {
+		handler := &NCANodeV3Handler{
+			Api: &mockApiClient{
+				response: []byte(`{"status":400,"message":"Invalid password"}`),
+				err:      &api.HTTPError{StatusCode: 400},
+			},
+		}
+
+		_, err := handler.SignWithSecurityHeader(context.Background(), "<xml></xml>", ``)
+		var nodeErr *NodeError
+		if !errors.As(err, &nodeErr) || nodeErr.Status != 400 || nodeErr.Message != "Invalid password" {
+			t.Errorf("Expected *NodeError from json body, got: %v", err)
+		}
+		if !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Expected ErrInvalidKey, got: %v", err)
+		}
+	})
+
+	t.Run("ProxyHtmlBody", func(t *testing.T) {
+		handler := &NCANodeV3Handler{
+			Api: &mockApiClient{
+				response: []byte(`<html>502 Bad Gateway</html>`),
+				err:      &api.HTTPError{StatusCode: 502, Body: []byte(`<html>502 Bad Gateway</html>`)},
+			},
+		}
+
+		_, err := handler.SignWithSecurityHeader(context.Background(), "<xml></xml>", ``)
+		var transportErr *TransportError
+		if !errors.As(err, &transportErr) {
+			t.Fatalf("Expected *TransportError, got: %v", err)
+		}
+		var httpErr *api.HTTPError
+		if !errors.As(err, &httpErr) || httpErr.StatusCode != 502 {
+			t.Errorf("Expected wrapped *api.HTTPError, got: %v", err)
+		}
+	})
+}
+
+func TestKeyProvider(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		m := &mockApiClient{response: []byte(`{"status":200,"message":"Success","cms":"MIIBAg=="}`)}
+		handler := &NCANodeV3Handler{
+			P12base64: "ignored",
+			Timeout:   time.Second,
+			Keys:      keys.Static("cm90YXRlZA==", "rotated"),
+			Api:       m,
+		}
+
+		if _, err := handler.SignCmsBytes(context.Background(), []byte("data"), false); err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+
+		if !strings.Contains(string(m.body), `"key":"cm90YXRlZA==","password":"rotated"`) {
+			t.Errorf("Expected provider key in request, got: %s", m.body)
+		}
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		m := &mockApiClient{}
+		fetchErr := errors.New("vault sealed")
+		handler := &NCANodeV3Handler{
+			Timeout: time.Second,
+			Keys:    keys.Func(func(ctx context.Context) (entities.Key, error) { return entities.Key{}, fetchErr }),
+			Api:     m,
+		}
+
+		_, err := handler.SignWithSecurityHeader(context.Background(), "<xml></xml>", ``)
+
+		var keyErr *KeyError
+		if !errors.As(err, &keyErr) || !errors.Is(err, fetchErr) || err.Error() != "SignXml: can't get key: vault sealed" {
+			t.Errorf("Expected *KeyError, got: %v", err)
+		}
+		if m.body != nil {
+			t.Errorf("Expected no request to be sent, got: %s", m.body)
+		}
+	})
+}
+
+func TestNCANodeV3Handler_Tsp(t *testing.T) {
+	t.Run("HashAlgorithm", func(t *testing.T) {
+		m := &mockApiClient{response: []byte(`{"status":200,"message":"Success","cms":"MIIBAg=="}`)}
+		handler := &NCANodeV3Handler{P12base64: "base64string", Timeout: time.Second, Api: m}
+
+		if _, err := handler.SignCmsBytes(context.Background(), []byte("data"), false, SignHashAlgorithm(types.GOST34311GT)); err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+		if !strings.Contains(string(m.body), `"withTsp":false,"detached":false}`) {
+			t.Errorf("Expected no timestamp unless requested, got: %s", m.body)
+		}
+
+		if _, err := handler.SignCmsBytes(context.Background(), []byte("data"), false, SignHashAlgorithm(types.GOST34311GT), SignWithTsp("")); err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+		if !strings.Contains(string(m.body), `"withTsp":true,"detached":false,"tsaPolicy":"TSA_GOSTGT_POLICY"}`) {
+			t.Errorf("Expected tsp settings in request, got: %s", m.body)
+		}
+	})
+
+	t.Run("SignXml", func(t *testing.T) {
+		m := &mockApiClient{}
+		handler := &NCANodeV3Handler{P12base64: "base64string", Timeout: time.Second, Api: m}
+
+		_, err := handler.SignWithSecurityHeader(context.Background(), "<xml></xml>", types.GOST34311)
+		if !errors.Is(err, ErrUnsupported) || err.Error() != "SignXml: hash algorithm GOST34311 is not supported by NCANode v3" {
+			t.Errorf("Expected *HashAlgorithmError, got: %v", err)
+		}
+		if m.body != nil {
+			t.Errorf("Expected no request to be sent, got: %s", m.body)
+		}
+	})
+
+	t.Run("HandlerTsp", func(t *testing.T) {
+		m := &mockApiClient{response: []byte(`{"status":200,"message":"Success","cms":"MIIBAg=="}`)}
+		handler := &NCANodeV3Handler{
+			P12base64: "base64string",
+			Timeout:   time.Second,
+			Tsp:       &entities.Tsp{Policy: types.TsaGostGtPolicy},
+			Api:       m,
+		}
+
+		if _, err := handler.SignCmsBytes(context.Background(), []byte("data"), false); err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+		if !strings.Contains(string(m.body), `"withTsp":true,"detached":false,"tsaPolicy":"TSA_GOSTGT_POLICY"}`) {
+			t.Errorf("Expected tsp settings in request, got: %s", m.body)
+		}
+	})
+
+	t.Run("UnsupportedHashAlgorithm", func(t *testing.T) {
+		m := &mockApiClient{}
+		handler := &NCANodeV3Handler{P12base64: "base64string", Timeout: time.Second, Api: m}
+
+		_, err := handler.SignWithSecurityHeader(context.Background(), "<xml></xml>", types.SHA256)
+		if !errors.Is(err, ErrUnsupported) || err.Error() != "SignXml: hash algorithm SHA256 is not supported by NCANode v3" {
+			t.Errorf("Expected *UnsupportedError, got: %v", err)
+		}
+
+		_, err = handler.SignCmsBytes(context.Background(), []byte("data"), false, SignHashAlgorithm(types.GOST34311), SignWithTsp(types.TsaGostGtPolicy))
+		if err == nil || errors.Is(err, ErrUnsupported) {
+			t.Errorf("Expected conflicting TSA policy error, got: %v", err)
+		}
+		if m.body != nil {
+			t.Errorf("Expected no request to be sent, got: %s", m.body)
+		}
 	})
 }
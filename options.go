@@ -0,0 +1,210 @@
+package goncanode
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/nbah1990/goncanode/entities"
+	"github.com/nbah1990/goncanode/pkcs12"
+	"github.com/nbah1990/goncanode/types"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultTimeout is applied by New when Options.Timeout is zero.
+	DefaultTimeout = 30 * time.Second
+	// DefaultVersion is applied by New and Create when Options.Version is nil.
+	DefaultVersion = types.NCAnodeV10
+)
+
+type OptionError struct {
+	Field   string
+	Message string
+	// Err is the cause when there is one, e.g. ErrInvalidKey for a key container the password doesn't open.
+	Err error
+}
+
+func (e *OptionError) Error() string {
+	return fmt.Sprintf(`%s: %s`, e.Field, e.Message)
+}
+
+func (e *OptionError) Unwrap() error {
+	return e.Err
+}
+
+// OptionsError lists every problem found in entities.Options, each one is an *OptionError.
+type OptionsError struct {
+	Problems []*OptionError
+}
+
+func (e *OptionsError) Error() string {
+	s := make([]string, 0, len(e.Problems))
+	for _, p := range e.Problems {
+		s = append(s, p.Error())
+	}
+
+	return `invalid options: ` + strings.Join(s, `; `)
+}
+
+func (e *OptionsError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Problems))
+	for _, p := range e.Problems {
+		errs = append(errs, p)
+	}
+
+	return errs
+}
+
+func (e *OptionsError) add(field string, format string, args ...interface{}) {
+	e.Problems = append(e.Problems, &OptionError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// ValidateOptions checks o the same way New does without creating a handler.
+func ValidateOptions(o entities.Options) error {
+	e := &OptionsError{}
+
+	if o.ServiceUrl == `` && len(o.ServiceUrls) == 0 {
+		e.add(`ServiceUrl`, `is required`)
+	} else if o.ServiceUrl != `` && len(o.ServiceUrls) > 0 {
+		e.add(`ServiceUrl`, `can't be combined with ServiceUrls, list every replica in ServiceUrls`)
+	} else if o.ServiceUrl != `` {
+		validateServiceUrl(e, `ServiceUrl`, o.ServiceUrl)
+	}
+
+	for i, u := range o.ServiceUrls {
+		validateServiceUrl(e, fmt.Sprintf(`ServiceUrls[%d]`, i), u)
+	}
+
+	if o.Keys != nil {
+		if o.P12base64 != `` || o.P12pass != `` || o.P12File != `` || o.P12Reader != nil {
+			e.add(`Keys`, `can't be combined with P12base64, P12File, P12Reader and P12pass`)
+		}
+	} else if o.P12File != `` || o.P12Reader != nil {
+		if o.P12base64 != `` || (o.P12File != `` && o.P12Reader != nil) {
+			e.add(`P12File`, `only one of P12base64, P12File and P12Reader can be set`)
+		}
+	} else if o.P12base64 == `` {
+		e.add(`P12base64`, `is required unless P12File, P12Reader or Keys is set`)
+	} else if _, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(o.P12base64), ``)); err != nil {
+		e.add(`P12base64`, `is not valid base64: %s`, err)
+	}
+
+	if o.Timeout < 0 {
+		e.add(`Timeout`, `must not be negative, got %s`, o.Timeout)
+	}
+
+	if o.TLS != nil {
+		if o.HTTPClient != nil {
+			e.add(`TLS`, `can't be combined with HTTPClient, configure its transport instead`)
+		} else if _, err := o.TLS.Build(); err != nil {
+			e.add(`TLS`, `%s`, err)
+		}
+	}
+
+	if o.Version != nil && !knownVersion(*o.Version) {
+		e.add(`Version`, `unknown version %q`, *o.Version)
+	}
+
+	if o.Tsp != nil && o.Version != nil && *o.Version != types.NCAnodeV30 {
+		e.add(`Tsp`, `is only supported by NCANode v3`)
+	}
+
+	if len(e.Problems) > 0 {
+		return e
+	}
+
+	return nil
+}
+
+// loadKey reads P12File or P12Reader into P12base64 and checks the container opens with P12pass
+// and holds KeyAlias, P12base64 itself is only checked when VerifyKey is set.
+func loadKey(o entities.Options) (entities.Options, error) {
+	field := `P12base64`
+
+	var der []byte
+	var err error
+	switch {
+	case o.P12File != ``:
+		field = `P12File`
+		der, err = os.ReadFile(o.P12File)
+	case o.P12Reader != nil:
+		field = `P12Reader`
+		der, err = io.ReadAll(o.P12Reader)
+	case o.VerifyKey && o.P12base64 != ``:
+		der, err = base64.StdEncoding.DecodeString(strings.Join(strings.Fields(o.P12base64), ``))
+	default:
+		return o, nil
+	}
+
+	if err != nil {
+		return o, optionError(field, fmt.Errorf(`can't be read: %w`, err))
+	}
+
+	if err := checkKey(der, o.P12pass, o.KeyAlias); err != nil {
+		return o, optionError(field, err)
+	}
+
+	o.P12base64 = base64.StdEncoding.EncodeToString(der)
+	o.P12File = ``
+	o.P12Reader = nil
+
+	return o, nil
+}
+
+// checkKey only fails on what the container surely tells: a wrong password or a missing alias.
+func checkKey(der []byte, password string, alias string) error {
+	info, err := pkcs12.Inspect(der, password)
+	if errors.Is(err, pkcs12.ErrIncorrectPassword) {
+		return fmt.Errorf(`P12pass doesn't open the key container: %w`, ErrInvalidKey)
+	}
+	// containers Inspect can't parse, e.g. BER encoded ones, may still be fine for NCANode, it will tell
+	if err != nil && !errors.Is(err, pkcs12.ErrUnsupportedMac) && !errors.Is(err, pkcs12.ErrNoMac) {
+		return nil
+	}
+
+	// keys in encrypted safe contents or without a friendly name can't be matched by alias locally
+	if alias == `` || (info.Keys == 0 && info.Encrypted) || info.Keys > len(info.Aliases) {
+		return nil
+	}
+
+	for _, a := range info.Aliases {
+		if strings.EqualFold(a, alias) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf(`%w: alias %q not found, the container has %q`, ErrInvalidKey, alias, info.Aliases)
+}
+
+func optionError(field string, err error) error {
+	return &OptionsError{Problems: []*OptionError{{Field: field, Message: err.Error(), Err: err}}}
+}
+
+func validateServiceUrl(e *OptionsError, field string, s string) {
+	if u, err := url.Parse(s); err != nil {
+		e.add(field, `can't be parsed: %s`, err)
+	} else if (u.Scheme != `http` && u.Scheme != `https`) || u.Host == `` {
+		e.add(field, `must be an absolute http or https url, got %q`, s)
+	}
+}
+
+func withDefaults(o entities.Options) entities.Options {
+	if o.Version == nil {
+		v := DefaultVersion
+		o.Version = &v
+	}
+
+	if o.Timeout == 0 {
+		o.Timeout = DefaultTimeout
+	}
+
+	return o
+}
+
+func knownVersion(v types.Version) bool {
+	return v == types.NCAnodeV10 || v == types.NCAnodeV20 || v == types.NCAnodeV30
+}
@@ -0,0 +1,173 @@
+package goncanode
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/nbah1990/goncanode/api"
+	"github.com/nbah1990/goncanode/entities"
+	"github.com/nbah1990/goncanode/keys"
+	"github.com/nbah1990/goncanode/types"
+)
+
+func TestValidateOptions(t *testing.T) {
+	valid := entities.Options{
+		ServiceUrl: "http://127.0.0.1:14579",
+		P12base64:  "MIIB\nAg==",
+		P12pass:    "password",
+		Timeout:    time.Second,
+	}
+
+	unknownVersion := types.Version("4.0")
+	v1, v3 := types.NCAnodeV10, types.NCAnodeV30
+
+	tests := []struct {
+		name   string
+		modify func(o *entities.Options)
+		fields []string
+	}{
+		{"Valid", func(o *entities.Options) {}, nil},
+		{"ZeroTimeout", func(o *entities.Options) { o.Timeout = 0 }, nil},
+		{"EmptyServiceUrl", func(o *entities.Options) { o.ServiceUrl = "" }, []string{"ServiceUrl"}},
+		{"RelativeServiceUrl", func(o *entities.Options) { o.ServiceUrl = "127.0.0.1:14579" }, []string{"ServiceUrl"}},
+		{"UnsupportedScheme", func(o *entities.Options) { o.ServiceUrl = "ftp://example.com" }, []string{"ServiceUrl"}},
+		{"EmptyP12", func(o *entities.Options) { o.P12base64 = "" }, []string{"P12base64"}},
+		{"InvalidP12", func(o *entities.Options) { o.P12base64 = "MIIB%" }, []string{"P12base64"}},
+		{"NegativeTimeout", func(o *entities.Options) { o.Timeout = -1 }, []string{"Timeout"}},
+		{"UnknownVersion", func(o *entities.Options) { o.Version = &unknownVersion }, []string{"Version"}},
+		{"ServiceUrlsOnly", func(o *entities.Options) {
+			o.ServiceUrl = ""
+			o.ServiceUrls = []string{"http://a:14579", "http://b:14579"}
+		}, nil},
+		{"InvalidServiceUrls", func(o *entities.Options) {
+			o.ServiceUrl = ""
+			o.ServiceUrls = []string{"http://a:14579", "b:14579"}
+		}, []string{"ServiceUrls[1]"}},
+		{"ServiceUrlWithServiceUrls", func(o *entities.Options) { o.ServiceUrls = []string{"http://a:14579"} }, []string{"ServiceUrl"}},
+		{"InvalidTLS", func(o *entities.Options) { o.TLS = &api.TLSConfig{CAPEM: []byte("not a pem")} }, []string{"TLS"}},
+		{"TLSWithHTTPClient", func(o *entities.Options) {
+			o.TLS = &api.TLSConfig{ServerName: "ncanode"}
+			o.HTTPClient = &http.Client{}
+		}, []string{"TLS"}},
+		{"KeysOnly", func(o *entities.Options) { o.P12base64 = ""; o.P12pass = ""; o.Keys = keys.Static("MIIBAg==", "") }, nil},
+		{"KeysWithP12", func(o *entities.Options) { o.Keys = keys.Static("MIIBAg==", "") }, []string{"Keys"}},
+		{"P12File", func(o *entities.Options) { o.P12base64 = ""; o.P12File = "key.p12" }, nil},
+		{"P12FileWithBase64", func(o *entities.Options) { o.P12File = "key.p12" }, []string{"P12File"}},
+		{"TspV3", func(o *entities.Options) { o.Version = &v3; o.Tsp = &entities.Tsp{Policy: types.TsaGostPolicy} }, nil},
+		{"TspV1", func(o *entities.Options) { o.Version = &v1; o.Tsp = &entities.Tsp{Policy: types.TsaGostPolicy} }, []string{"Tsp"}},
+		{"Multiple", func(o *entities.Options) { o.ServiceUrl = ""; o.P12base64 = "" }, []string{"ServiceUrl", "P12base64"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := valid
+			tt.modify(&o)
+
+			err := ValidateOptions(o)
+			if tt.fields == nil {
+				if err != nil {
+					t.Errorf("Expected no errors, got: %v", err)
+				}
+				return
+			}
+
+			var optionsErr *OptionsError
+			if !errors.As(err, &optionsErr) {
+				t.Fatalf("Expected *OptionsError, got: %v", err)
+			}
+			if len(optionsErr.Problems) != len(tt.fields) {
+				t.Fatalf("Expected %d problems, got: %v", len(tt.fields), err)
+			}
+			for i, f := range tt.fields {
+				if optionsErr.Problems[i].Field != f {
+					t.Errorf("Expected problem with %s, got: %v", f, optionsErr.Problems[i])
+				}
+			}
+
+			var optionErr *OptionError
+			if !errors.As(err, &optionErr) || optionErr.Field != tt.fields[0] {
+				t.Errorf("Expected errors.As to find *OptionError for %s, got: %v", tt.fields[0], optionErr)
+			}
+		})
+	}
+}
+
+func TestNew_LoadKey(t *testing.T) {
+	der, err := os.ReadFile("pkcs12/testdata/sha256.p12")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := entities.Options{ServiceUrl: "http://127.0.0.1:14579", P12pass: "secret"}
+
+	tests := []struct {
+		name   string
+		modify func(o *entities.Options)
+		field  string
+	}{
+		{"File", func(o *entities.Options) { o.P12File = "pkcs12/testdata/sha256.p12"; o.KeyAlias = "SIGNER" }, ""},
+		{"Reader", func(o *entities.Options) { o.P12Reader = bytes.NewReader(der) }, ""},
+		{"VerifyBase64", func(o *entities.Options) { o.P12base64 = base64.StdEncoding.EncodeToString(der); o.VerifyKey = true }, ""},
+		{"MissingFile", func(o *entities.Options) { o.P12File = "pkcs12/testdata/missing.p12" }, "P12File"},
+		{"WrongPassword", func(o *entities.Options) { o.P12File = "pkcs12/testdata/sha256.p12"; o.P12pass = "wrong" }, "P12File"},
+		{"WrongAlias", func(o *entities.Options) { o.P12Reader = bytes.NewReader(der); o.KeyAlias = "auth" }, "P12Reader"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := base
+			tt.modify(&o)
+
+			h, err := New(o)
+			if tt.field == "" {
+				if err != nil {
+					t.Fatalf("Expected no errors, got: %v", err)
+				}
+				if k := h.(*NCANodeV1Handler).P12base64; k != base64.StdEncoding.EncodeToString(der) {
+					t.Errorf("Expected loaded key, got: %s", k)
+				}
+				return
+			}
+
+			var optionErr *OptionError
+			if !errors.As(err, &optionErr) || optionErr.Field != tt.field {
+				t.Fatalf("Expected *OptionError for %s, got: %v", tt.field, err)
+			}
+			if tt.name != "MissingFile" && !errors.Is(err, ErrInvalidKey) {
+				t.Errorf("Expected ErrInvalidKey, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestNew_LoadUnverifiableKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(o *entities.Options)
+	}{
+		{"Encrypted", func(o *entities.Options) { o.P12File = "pkcs12/testdata/encrypted.p12"; o.KeyAlias = "signer" }},
+		{"NoMac", func(o *entities.Options) { o.P12File = "pkcs12/testdata/nomac.p12"; o.KeyAlias = "signer" }},
+		{"UnnamedKey", func(o *entities.Options) {
+			o.P12File = "pkcs12/testdata/noname.p12"
+			o.P12pass = "secret"
+			o.KeyAlias = "any"
+		}},
+		{"NotParsed", func(o *entities.Options) { o.P12base64 = "MIIBAg=="; o.VerifyKey = true }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := entities.Options{ServiceUrl: "http://127.0.0.1:14579", P12pass: "unchecked"}
+			tt.modify(&o)
+
+			if _, err := New(o); err != nil {
+				t.Errorf("Expected the key to be left for NCANode to check, got: %v", err)
+			}
+		})
+	}
+}
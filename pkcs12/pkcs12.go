@@ -0,0 +1,272 @@
+// Package pkcs12 reads just enough of a PKCS#12 container to check its password and list its key aliases locally,
+// the keys themselves are never decrypted.
+package pkcs12
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"hash"
+	"unicode/utf16"
+)
+
+var (
+	ErrIncorrectPassword = errors.New("pkcs12: incorrect password")
+	// ErrUnsupportedMac is returned along with the aliases when the MAC algorithm is unknown, the password is unchecked then.
+	ErrUnsupportedMac = errors.New("pkcs12: unsupported mac algorithm")
+	// ErrNoMac is returned along with the aliases for a container without MAC, the password is unchecked then.
+	ErrNoMac = errors.New("pkcs12: container has no mac")
+)
+
+var (
+	oidData         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidKeyBag       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 1}
+	oidShroudedKey  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 2}
+	oidFriendlyName = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 20}
+	oidSHA1         = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidSHA256       = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA384       = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidSHA512       = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+)
+
+type pfx struct {
+	Version  int
+	AuthSafe contentInfo
+	MacData  macData `asn1:"optional"`
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"tag:0,explicit,optional"`
+}
+
+type macData struct {
+	Mac        digestInfo
+	MacSalt    []byte
+	Iterations int `asn1:"optional,default:1"`
+}
+
+type digestInfo struct {
+	Algorithm algorithmIdentifier
+	Digest    []byte
+}
+
+type algorithmIdentifier struct {
+	Algorithm  asn1.ObjectIdentifier
+	Parameters asn1.RawValue `asn1:"optional"`
+}
+
+type safeBag struct {
+	Id         asn1.ObjectIdentifier
+	Value      asn1.RawValue `asn1:"tag:0,explicit"`
+	Attributes []attribute   `asn1:"set,optional"`
+}
+
+type attribute struct {
+	Id     asn1.ObjectIdentifier
+	Values asn1.RawValue `asn1:"set"`
+}
+
+// Info describes a container, Aliases holds the friendly names of private keys kept outside of encrypted safe contents.
+type Info struct {
+	Aliases []string
+	// Keys counts private keys found, including ones without a friendly name.
+	Keys int
+	// Encrypted is set when some safe contents are encrypted, keys inside them are neither counted nor listed,
+	// so Keys == 0 means unknown rather than none then.
+	Encrypted bool
+}
+
+// Inspect verifies the container MAC with password and lists its key aliases.
+func Inspect(der []byte, password string) (Info, error) {
+	var info Info
+
+	var p pfx
+	rest, err := asn1.Unmarshal(der, &p)
+	if err != nil {
+		return info, fmt.Errorf(`pkcs12: not a pkcs12 container: %w`, err)
+	}
+	if len(rest) > 0 {
+		return info, errors.New(`pkcs12: trailing data after container`)
+	}
+	if p.Version != 3 {
+		return info, fmt.Errorf(`pkcs12: unsupported version %d`, p.Version)
+	}
+	if !p.AuthSafe.ContentType.Equal(oidData) {
+		return info, errors.New(`pkcs12: only password integrity mode is supported`)
+	}
+
+	var authSafe []byte
+	if _, err := asn1.Unmarshal(p.AuthSafe.Content.Bytes, &authSafe); err != nil {
+		return info, fmt.Errorf(`pkcs12: malformed auth safe: %w`, err)
+	}
+
+	macErr := verifyMac(p.MacData, authSafe, password)
+	if macErr != nil && !errors.Is(macErr, ErrUnsupportedMac) && !errors.Is(macErr, ErrNoMac) {
+		return info, macErr
+	}
+
+	var contents []contentInfo
+	if _, err := asn1.Unmarshal(authSafe, &contents); err != nil {
+		return info, fmt.Errorf(`pkcs12: malformed auth safe: %w`, err)
+	}
+
+	for _, c := range contents {
+		if !c.ContentType.Equal(oidData) {
+			info.Encrypted = true
+			continue
+		}
+
+		var data []byte
+		if _, err := asn1.Unmarshal(c.Content.Bytes, &data); err != nil {
+			return info, fmt.Errorf(`pkcs12: malformed safe contents: %w`, err)
+		}
+
+		var bags []safeBag
+		if _, err := asn1.Unmarshal(data, &bags); err != nil {
+			return info, fmt.Errorf(`pkcs12: malformed safe contents: %w`, err)
+		}
+
+		for _, b := range bags {
+			if !b.Id.Equal(oidKeyBag) && !b.Id.Equal(oidShroudedKey) {
+				continue
+			}
+
+			info.Keys++
+			if name, ok := friendlyName(b.Attributes); ok {
+				info.Aliases = append(info.Aliases, name)
+			}
+		}
+	}
+
+	return info, macErr
+}
+
+func verifyMac(m macData, content []byte, password string) error {
+	if m.Mac.Algorithm.Algorithm == nil {
+		return ErrNoMac
+	}
+
+	var h func() hash.Hash
+	var u, v int
+	switch alg := m.Mac.Algorithm.Algorithm; {
+	case alg.Equal(oidSHA1):
+		h, u, v = sha1.New, sha1.Size, sha1.BlockSize
+	case alg.Equal(oidSHA256):
+		h, u, v = sha256.New, sha256.Size, sha256.BlockSize
+	case alg.Equal(oidSHA384):
+		h, u, v = sha512.New384, sha512.Size384, sha512.BlockSize
+	case alg.Equal(oidSHA512):
+		h, u, v = sha512.New, sha512.Size, sha512.BlockSize
+	default:
+		return fmt.Errorf(`%w %s`, ErrUnsupportedMac, alg)
+	}
+
+	// an empty password is encoded either as an empty string or as a lone terminator depending on the producer
+	candidates := [][]byte{bmpString(password)}
+	if password == `` {
+		candidates = append(candidates, nil)
+	}
+
+	for _, pw := range candidates {
+		key := deriveKey(h, u, v, m.MacSalt, pw, m.Iterations, 3, u)
+		mac := hmac.New(h, key)
+		mac.Write(content)
+		if hmac.Equal(mac.Sum(nil), m.Mac.Digest) {
+			return nil
+		}
+	}
+
+	return ErrIncorrectPassword
+}
+
+// deriveKey is the PKCS#12 key derivation function from RFC 7292 appendix B.2, id 3 derives MAC keys.
+func deriveKey(h func() hash.Hash, u int, v int, salt []byte, password []byte, iterations int, id byte, size int) []byte {
+	D := make([]byte, v)
+	for i := range D {
+		D[i] = id
+	}
+
+	fill := func(b []byte) []byte {
+		if len(b) == 0 {
+			return nil
+		}
+		n := v * ((len(b) + v - 1) / v)
+		s := make([]byte, n)
+		for i := range s {
+			s[i] = b[i%len(b)]
+		}
+		return s
+	}
+
+	I := append(fill(salt), fill(password)...)
+
+	var out []byte
+	for len(out) < size {
+		A := append(append([]byte(nil), D...), I...)
+		for i := 0; i < iterations; i++ {
+			d := h()
+			d.Write(A)
+			A = d.Sum(nil)
+		}
+		out = append(out, A...)
+
+		if len(out) >= size {
+			break
+		}
+
+		B := make([]byte, v)
+		for i := range B {
+			B[i] = A[i%u]
+		}
+
+		// I_j = (I_j + B + 1) mod 2^(v*8) for every v-byte block of I
+		for j := 0; j < len(I); j += v {
+			carry := 1
+			for k := v - 1; k >= 0; k-- {
+				sum := int(I[j+k]) + int(B[k]) + carry
+				I[j+k] = byte(sum)
+				carry = sum >> 8
+			}
+		}
+	}
+
+	return out[:size]
+}
+
+// bmpString encodes password as big-endian UTF-16 with a two byte terminator.
+func bmpString(s string) []byte {
+	u := utf16.Encode([]rune(s))
+	b := make([]byte, 0, 2*len(u)+2)
+	for _, r := range u {
+		b = append(b, byte(r>>8), byte(r))
+	}
+
+	return append(b, 0, 0)
+}
+
+func friendlyName(attrs []attribute) (string, bool) {
+	for _, a := range attrs {
+		if !a.Id.Equal(oidFriendlyName) {
+			continue
+		}
+
+		var v asn1.RawValue
+		if _, err := asn1.Unmarshal(a.Values.Bytes, &v); err != nil || v.Tag != asn1.TagBMPString || len(v.Bytes)%2 != 0 {
+			return ``, false
+		}
+
+		u := make([]uint16, 0, len(v.Bytes)/2)
+		for i := 0; i < len(v.Bytes); i += 2 {
+			u = append(u, uint16(v.Bytes[i])<<8|uint16(v.Bytes[i+1]))
+		}
+
+		return string(utf16.Decode(u)), true
+	}
+
+	return ``, false
+}
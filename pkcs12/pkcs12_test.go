@@ -0,0 +1,129 @@
+package pkcs12
+
+import (
+	"encoding/asn1"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	tests := []struct {
+		file     string
+		password string
+		aliases  []string
+		err      error
+	}{
+		{"testdata/sha256.p12", "secret", []string{"signer"}, nil},
+		{"testdata/sha1.p12", "secret", []string{"RSA Signer"}, nil},
+		{"testdata/empty.p12", "", []string{"signer"}, nil},
+		{"testdata/sha256.p12", "wrong", nil, ErrIncorrectPassword},
+		{"testdata/sha1.p12", "", nil, ErrIncorrectPassword},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file+"/"+tt.password, func(t *testing.T) {
+			der, err := os.ReadFile(tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			info, err := Inspect(der, tt.password)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected %v, got: %v", tt.err, err)
+			}
+			if tt.err == nil && (!reflect.DeepEqual(info.Aliases, tt.aliases) || info.Keys != 1) {
+				t.Errorf("Unexpected info: %+v", info)
+			}
+		})
+	}
+}
+
+func TestInspect_Malformed(t *testing.T) {
+	for name, der := range map[string][]byte{
+		"Empty":    nil,
+		"NotAsn1":  []byte("not a container"),
+		"Sequence": {0x30, 0x03, 0x02, 0x01, 0x03},
+	} {
+		if _, err := Inspect(der, "secret"); err == nil || errors.Is(err, ErrIncorrectPassword) {
+			t.Errorf("%s: expected malformed container error, got: %v", name, err)
+		}
+	}
+}
+
+// container builds an unprotected container from safe contents of the given types, each holding bags.
+func container(t *testing.T, bags []safeBag, types ...asn1.ObjectIdentifier) []byte {
+	type rawContentInfo struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue
+	}
+	explicit := func(b []byte) asn1.RawValue {
+		return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: b}
+	}
+	octets := func(v interface{}) []byte {
+		b, err := asn1.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		o, err := asn1.Marshal(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return o
+	}
+
+	var contents []rawContentInfo
+	for _, typ := range types {
+		contents = append(contents, rawContentInfo{ContentType: typ, Content: explicit(octets(bags))})
+	}
+
+	der, err := asn1.Marshal(struct {
+		Version  int
+		AuthSafe rawContentInfo
+	}{3, rawContentInfo{ContentType: oidData, Content: explicit(octets(contents))}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return der
+}
+
+func TestInspect_Unverifiable(t *testing.T) {
+	// raw values are encoded as given, so the explicit tag of Value has to be spelled out
+	bag := safeBag{
+		Id:         oidKeyBag,
+		Value:      asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: []byte{0x05, 0x00}},
+		Attributes: []attribute{{Id: oidFriendlyName, Values: asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: bmpValue(t, "signer")}}},
+	}
+	oidEncryptedData := asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 6}
+
+	t.Run("NoMac", func(t *testing.T) {
+		info, err := Inspect(container(t, []safeBag{bag}, oidData), "anything")
+		if !errors.Is(err, ErrNoMac) {
+			t.Fatalf("Expected ErrNoMac, got: %v", err)
+		}
+		if info.Keys != 1 || !reflect.DeepEqual(info.Aliases, []string{"signer"}) || info.Encrypted {
+			t.Errorf("Unexpected info: %+v", info)
+		}
+	})
+
+	t.Run("Encrypted", func(t *testing.T) {
+		info, err := Inspect(container(t, []safeBag{bag}, oidEncryptedData), "")
+		if !errors.Is(err, ErrNoMac) {
+			t.Fatalf("Expected ErrNoMac, got: %v", err)
+		}
+		if info.Keys != 0 || !info.Encrypted {
+			t.Errorf("Expected keys to be unknown, got: %+v", info)
+		}
+	})
+}
+
+func bmpValue(t *testing.T, s string) []byte {
+	b := bmpString(s)
+	v, err := asn1.Marshal(asn1.RawValue{Class: asn1.ClassUniversal, Tag: 30, Bytes: b[:len(b)-2]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
@@ -0,0 +1,146 @@
+package goncanode
+
+import (
+	"context"
+	"github.com/nbah1990/goncanode/entities"
+	"github.com/nbah1990/goncanode/types"
+	"time"
+)
+
+// SignOptions override handler-wide settings for a single signing call, zero fields keep them.
+type SignOptions struct {
+	// Key replaces the handler's key and key provider.
+	Key      *entities.Key
+	KeyAlias string
+	Timeout  time.Duration
+	// HashAlgorithm replaces the one passed to SignWithSecurityHeader.
+	HashAlgorithm types.HashAlgorithm
+	// TrimXml asks NCANode v3 to drop whitespace between XML elements before signing.
+	TrimXml bool
+	// WithTsp adds a timestamp from the TSA configured on the NCANode side to CMS signatures, TsaPolicy selects its policy.
+	WithTsp   bool
+	TsaPolicy types.TsaPolicy
+}
+
+type SignOption func(o *SignOptions)
+
+func SignKey(k entities.Key) SignOption {
+	return func(o *SignOptions) { o.Key = &k }
+}
+
+func SignKeyAlias(alias string) SignOption {
+	return func(o *SignOptions) { o.KeyAlias = alias }
+}
+
+func SignTimeout(d time.Duration) SignOption {
+	return func(o *SignOptions) { o.Timeout = d }
+}
+
+func SignHashAlgorithm(h types.HashAlgorithm) SignOption {
+	return func(o *SignOptions) { o.HashAlgorithm = h }
+}
+
+func SignTrimXml(trim bool) SignOption {
+	return func(o *SignOptions) { o.TrimXml = trim }
+}
+
+func SignWithTsp(policy types.TsaPolicy) SignOption {
+	return func(o *SignOptions) {
+		o.WithTsp = true
+		o.TsaPolicy = policy
+	}
+}
+
+// SignWith applies the non-zero fields of a prepared SignOptions, so options before it keep the rest
+// and later ones still override its fields.
+func SignWith(s SignOptions) SignOption {
+	return func(o *SignOptions) {
+		if s.Key != nil {
+			o.Key = s.Key
+		}
+		if s.KeyAlias != `` {
+			o.KeyAlias = s.KeyAlias
+		}
+		if s.Timeout != 0 {
+			o.Timeout = s.Timeout
+		}
+		if s.HashAlgorithm != `` {
+			o.HashAlgorithm = s.HashAlgorithm
+		}
+		if s.TrimXml {
+			o.TrimXml = true
+		}
+		if s.WithTsp {
+			o.WithTsp = true
+		}
+		if s.TsaPolicy != `` {
+			o.TsaPolicy = s.TsaPolicy
+		}
+	}
+}
+
+func signOptions(opts []SignOption) SignOptions {
+	var o SignOptions
+	for _, f := range opts {
+		f(&o)
+	}
+
+	return o
+}
+
+type keyOverrideKey struct{}
+type timeoutKey struct{}
+
+// context carries the key, alias and timeout overrides down to resolveKey and execute.
+func (o SignOptions) context(ctx context.Context) context.Context {
+	if o.Key != nil {
+		ctx = context.WithValue(ctx, keyOverrideKey{}, *o.Key)
+	}
+	if o.KeyAlias != `` {
+		ctx = withKeyAlias(ctx, o.KeyAlias)
+	}
+	if o.Timeout > 0 {
+		ctx = context.WithValue(ctx, timeoutKey{}, o.Timeout)
+	}
+
+	return ctx
+}
+
+func keyOverride(ctx context.Context) (entities.Key, bool) {
+	k, ok := ctx.Value(keyOverrideKey{}).(entities.Key)
+	return k, ok
+}
+
+// callTimeout is the per call timeout if one was set, otherwise the handler's one.
+func callTimeout(ctx context.Context, d time.Duration) time.Duration {
+	if t, ok := ctx.Value(timeoutKey{}).(time.Duration); ok {
+		return t
+	}
+
+	return d
+}
+
+type feature struct {
+	name      string
+	requested bool
+}
+
+// checkHash rejects hash algorithms NCANode of version v can't timestamp with, the empty one leaves the choice to NCANode.
+func checkHash(op string, h types.HashAlgorithm, v types.Version) error {
+	if h != `` && !h.SupportedBy(v) {
+		return &HashAlgorithmError{Op: op, Algorithm: h, Version: v}
+	}
+
+	return nil
+}
+
+// unsupported reports the first requested feature the NCANode version lacks.
+func unsupported(op string, version string, features ...feature) error {
+	for _, f := range features {
+		if f.requested {
+			return &UnsupportedError{Op: op, Feature: f.name, Version: version}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,164 @@
+package goncanode
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/nbah1990/goncanode/entities"
+	"github.com/nbah1990/goncanode/keys"
+	"github.com/nbah1990/goncanode/types"
+	"strings"
+	"testing"
+	"time"
+)
+
+type deadlineApiClient struct {
+	mockApiClient
+	deadline time.Duration
+}
+
+func (m *deadlineApiClient) Request(ctx context.Context, method string, url string, data *bytes.Buffer) ([]byte, error) {
+	if d, ok := ctx.Deadline(); ok {
+		m.deadline = time.Until(d)
+	}
+	return m.mockApiClient.Request(ctx, method, url, data)
+}
+
+func TestSignOptions(t *testing.T) {
+	t.Run("V3", func(t *testing.T) {
+		m := &deadlineApiClient{mockApiClient: mockApiClient{response: []byte(`{"status":200,"message":"Success","xml":"<signedXml></signedXml>"}`)}}
+		handler := &NCANodeV3Handler{
+			Timeout: time.Second,
+			Keys: keys.Func(func(ctx context.Context) (entities.Key, error) {
+				t.Errorf("Key provider must not be asked when the key is set per call")
+				return entities.Key{}, errors.New("unexpected")
+			}),
+			Api: m,
+		}
+
+		_, err := handler.SignWithSecurityHeader(context.Background(), "<xml></xml>", ``,
+			SignKey(entities.Key{P12base64: "b3RoZXI=", Password: "other"}),
+			SignKeyAlias("sign"),
+			SignTrimXml(true),
+			SignTimeout(time.Minute),
+		)
+		if err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+
+		expected := `{"xml":"\u003cxml\u003e\u003c/xml\u003e","key":"b3RoZXI=","password":"other","keyAlias":"sign","trimXml":true}`
+		if string(m.body) != expected {
+			t.Errorf("Expected %s, got %s", expected, m.body)
+		}
+		if m.deadline < 30*time.Second {
+			t.Errorf("Expected per call timeout, got deadline in %s", m.deadline)
+		}
+	})
+
+	t.Run("KeyWithoutHandlerAlias", func(t *testing.T) {
+		m := &mockApiClient{response: []byte(`{"status":200,"message":"Success","xml":"<signedXml></signedXml>"}`)}
+		handler := &NCANodeV3Handler{P12base64: "base64string", KeyAlias: "handler", Timeout: time.Second, Api: m}
+
+		_, err := handler.SignWithSecurityHeader(context.Background(), "<xml></xml>", ``, SignKey(entities.Key{P12base64: "b3RoZXI=", Password: "other"}))
+		if err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+		if !strings.Contains(string(m.body), `"keyAlias":null`) {
+			t.Errorf("Expected the handler alias not to apply to a per call key, got: %s", m.body)
+		}
+	})
+
+	t.Run("V3Cms", func(t *testing.T) {
+		m := &mockApiClient{response: []byte(`{"status":200,"message":"Success","cms":"MIIBAg=="}`)}
+		handler := &NCANodeV3Handler{P12base64: "base64string", Timeout: time.Second, Api: m}
+
+		if _, err := handler.SignCmsBytes(context.Background(), []byte("data"), true, SignWithTsp("TSA_GOST_POLICY")); err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+		if !strings.Contains(string(m.body), `"withTsp":true,"detached":true,"tsaPolicy":"TSA_GOST_POLICY"`) {
+			t.Errorf("Expected tsp settings in request, got: %s", m.body)
+		}
+	})
+
+	t.Run("V1", func(t *testing.T) {
+		m := &mockApiClientV1{response: []byte(`{"status":0,"message":"","result":{"cms":"MIIBAg=="}}`)}
+		handler := &NCANodeV1Handler{P12base64: "base64string", Timeout: time.Second, Api: m}
+
+		_, err := handler.SignCmsBytes(context.Background(), []byte("data"), false, SignWith(SignOptions{
+			WithTsp:       true,
+			TsaPolicy:     "TSA_GOST_POLICY",
+			HashAlgorithm: types.GOST34311,
+		}))
+		if err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+		if !strings.Contains(string(m.body), `"createTsp":true,"useTsaPolicy":"TSA_GOST_POLICY","tspHashAlgorithm":"GOST34311"`) {
+			t.Errorf("Expected tsp settings in request, got: %s", m.body)
+		}
+
+		m.response = []byte(`{"status":0,"message":"","result":{"xml":"<signedXml></signedXml>"}}`)
+		if _, err := handler.SignWithSecurityHeader(context.Background(), "<xml></xml>", types.SHA256, SignHashAlgorithm(types.GOST34311)); err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+		if !strings.Contains(string(m.body), `"tspHashAlgorithm":"GOST34311"`) {
+			t.Errorf("Expected overridden hash algorithm, got: %s", m.body)
+		}
+	})
+
+	t.Run("V2Cms", func(t *testing.T) {
+		m := &mockApiClientV2{response: []byte(`{"status":0,"message":"","cms":"MIIBAg=="}`)}
+		handler := &NCANodeV2Handler{P12base64: "base64string", Timeout: time.Second, Api: m}
+
+		_, err := handler.SignCmsBytes(context.Background(), []byte("data"), false, SignWithTsp("TSA_GOST_POLICY"), SignHashAlgorithm(types.SHA256))
+		if err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+		if !strings.Contains(string(m.body), `"withTsp":true,"tsaPolicy":"TSA_GOST_POLICY","tspHashAlgorithm":"SHA256"`) {
+			t.Errorf("Expected tsp settings in request, got: %s", m.body)
+		}
+	})
+
+	t.Run("SignWithMerges", func(t *testing.T) {
+		k := entities.Key{P12base64: "b3RoZXI=", Password: "other"}
+		o := signOptions([]SignOption{
+			SignKey(k),
+			SignTimeout(time.Minute),
+			SignWith(SignOptions{KeyAlias: "sign", Timeout: time.Hour}),
+			SignHashAlgorithm(types.SHA256),
+		})
+
+		if o.Key == nil || *o.Key != k || o.KeyAlias != "sign" || o.Timeout != time.Hour || o.HashAlgorithm != types.SHA256 {
+			t.Errorf("Expected merged options, got: %+v", o)
+		}
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		m := &mockApiClientV1{}
+		handler := &NCANodeV1Handler{P12base64: "base64string", Timeout: time.Second, Api: m}
+
+		_, err := handler.SignWithSecurityHeader(context.Background(), "<xml></xml>", ``, SignTrimXml(true))
+
+		var unsupportedErr *UnsupportedError
+		if !errors.Is(err, ErrUnsupported) || !errors.As(err, &unsupportedErr) || err.Error() != "XML.signWithSecurityHeader: TrimXml is not supported by NCANode v1" {
+			t.Errorf("Expected *UnsupportedError, got: %v", err)
+		}
+		if m.body != nil {
+			t.Errorf("Expected no request to be sent, got: %s", m.body)
+		}
+	})
+
+	t.Run("UnknownHashAlgorithm", func(t *testing.T) {
+		m := &mockApiClientV1{}
+		tracer := &recordingTracer{}
+		handler := &NCANodeV1Handler{P12base64: "base64string", Timeout: time.Second, Tracer: tracer, Api: m}
+
+		_, err := handler.SignWithSecurityHeader(context.Background(), "<xml></xml>", "SHA-256")
+		var hashErr *HashAlgorithmError
+		if !errors.Is(err, types.ErrUnknownHashAlgorithm) || !errors.As(err, &hashErr) || err.Error() != `XML.signWithSecurityHeader: unknown hash algorithm "SHA-256"` {
+			t.Errorf("Expected *HashAlgorithmError, got: %v", err)
+		}
+		if m.body != nil || len(tracer.spans) != 0 {
+			t.Errorf("Expected no request to be sent, got: %s and %d spans", m.body, len(tracer.spans))
+		}
+	})
+}
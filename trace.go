@@ -0,0 +1,99 @@
+package goncanode
+
+import (
+	"context"
+	"errors"
+	"github.com/nbah1990/goncanode/api"
+	"github.com/nbah1990/goncanode/metrics"
+	"github.com/nbah1990/goncanode/tracing"
+	"github.com/nbah1990/goncanode/types"
+	"log/slog"
+	"time"
+)
+
+func startSpan(ctx context.Context, t tracing.Tracer, v types.Version, op string, endpoint string) (context.Context, tracing.Span) {
+	if t == nil {
+		return ctx, nil
+	}
+
+	return t.Start(ctx, `ncanode `+op,
+		tracing.String(tracing.AttrVersion, string(v)),
+		tracing.String(tracing.AttrOperation, op),
+		tracing.String(tracing.AttrEndpoint, endpoint),
+	)
+}
+
+func endSpan(s tracing.Span, start time.Time, status int, err error) {
+	if s == nil {
+		return
+	}
+
+	s.SetAttributes(
+		tracing.Int(tracing.AttrStatus, callStatus(status, err)),
+		tracing.Float64(tracing.AttrLatencyMs, float64(time.Since(start))/float64(time.Millisecond)),
+	)
+
+	if err != nil {
+		s.SetAttributes(tracing.String(tracing.AttrErrorClass, ErrorClass(err)))
+		s.RecordError(err)
+	}
+
+	s.End()
+}
+
+// failCall reports a call that failed before its request was built, e.g. with a KeyError, the way execute reports the others.
+func failCall(ctx context.Context, l *slog.Logger, t tracing.Tracer, m metrics.Collector, v types.Version, op string, endpoint string, start time.Time, err error) {
+	ctx, span := startSpan(ctx, t, v, op, endpoint)
+	endSpan(span, start, 0, err)
+	recordCall(m, v, op, endpoint, start, 0, err)
+	logCall(ctx, l, v, op, endpoint, start, 0, nil, nil, err)
+}
+
+// callStatus prefers the status carried by err over the one decoded from a successful response.
+func callStatus(status int, err error) int {
+	var nodeErr *NodeError
+	var httpErr *api.HTTPError
+	switch {
+	case errors.As(err, &nodeErr):
+		return nodeErr.Status
+	case errors.As(err, &httpErr):
+		return httpErr.StatusCode
+	}
+
+	return status
+}
+
+// ErrorClass sorts handler errors into a few stable groups suitable for span attributes and metric labels.
+func ErrorClass(err error) string {
+	var nodeErr *NodeError
+	var decodeErr *DecodeError
+	var transportErr *TransportError
+	var keyErr *KeyError
+
+	switch {
+	case err == nil:
+		return ``
+	case errors.Is(err, api.ErrCircuitOpen):
+		return `circuit_open`
+	case errors.Is(err, context.DeadlineExceeded):
+		return `timeout`
+	case errors.Is(err, context.Canceled):
+		return `canceled`
+	case errors.Is(err, ErrInvalidKey):
+		return `invalid_key`
+	case errors.Is(err, ErrExpiredCertificate):
+		return `expired_certificate`
+	case errors.Is(err, ErrRevokedCertificate):
+		return `revoked_certificate`
+	case errors.As(err, &nodeErr):
+		return `node`
+	case errors.As(err, &decodeErr):
+		return `decode`
+	case errors.As(err, &transportErr):
+		return `transport`
+	case errors.As(err, &keyErr):
+		return `key`
+	}
+
+	return `other`
+}
@@ -0,0 +1,165 @@
+package goncanode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/nbah1990/goncanode/api"
+	"github.com/nbah1990/goncanode/entities"
+	"github.com/nbah1990/goncanode/keys"
+	"github.com/nbah1990/goncanode/metrics"
+	"github.com/nbah1990/goncanode/tracing"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+type recordedSpan struct {
+	name  string
+	attrs map[string]interface{}
+	err   error
+	ended bool
+}
+
+func (s *recordedSpan) SetAttributes(attrs ...tracing.Attribute) {
+	for _, a := range attrs {
+		s.attrs[a.Key] = a.Value
+	}
+}
+
+func (s *recordedSpan) RecordError(err error) {
+	s.err = err
+}
+
+func (s *recordedSpan) End() {
+	s.ended = true
+}
+
+type recordingTracer struct {
+	spans []*recordedSpan
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string, attrs ...tracing.Attribute) (context.Context, tracing.Span) {
+	s := &recordedSpan{name: name, attrs: map[string]interface{}{}}
+	s.SetAttributes(attrs...)
+	t.spans = append(t.spans, s)
+	return ctx, s
+}
+
+func (t *recordingTracer) Inject(context.Context, http.Header) {}
+
+func TestTracing(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		tracer := &recordingTracer{}
+		handler := &NCANodeV3Handler{
+			P12base64: "base64string",
+			Timeout:   time.Second,
+			Tracer:    tracer,
+			Api:       &mockApiClient{response: []byte(`{"status":200,"message":"Success","xml":"<signedXml></signedXml>"}`)},
+		}
+
+		if _, err := handler.SignWithSecurityHeader(context.Background(), "<xml></xml>", ``); err != nil {
+			t.Fatalf("Expected no errors, got: %v", err)
+		}
+
+		if len(tracer.spans) != 1 {
+			t.Fatalf("Expected one span, got %d", len(tracer.spans))
+		}
+		s := tracer.spans[0]
+		if s.name != "ncanode SignXml" || !s.ended || s.err != nil {
+			t.Errorf("Unexpected span: %+v", s)
+		}
+		if s.attrs[tracing.AttrVersion] != "3.0" || s.attrs[tracing.AttrEndpoint] != "/wsse/sign" || s.attrs[tracing.AttrStatus] != 200 {
+			t.Errorf("Unexpected attributes: %v", s.attrs)
+		}
+		if _, ok := s.attrs[tracing.AttrLatencyMs]; !ok {
+			t.Errorf("Expected latency attribute, got: %v", s.attrs)
+		}
+	})
+
+	t.Run("NodeError", func(t *testing.T) {
+		tracer := &recordingTracer{}
+		handler := &NCANodeV2Handler{
+			P12base64: "base64string",
+			Timeout:   time.Second,
+			Tracer:    tracer,
+			Api:       &mockApiClientV2{response: []byte(`{"status":-1,"message":"Invalid password"}`)},
+		}
+
+		_, err := handler.VerifyXml(context.Background(), "<xml></xml>")
+		if err == nil {
+			t.Fatal("Expected error, got nil")
+		}
+
+		s := tracer.spans[0]
+		if s.attrs[tracing.AttrErrorClass] != "invalid_key" || s.attrs[tracing.AttrStatus] != -1 || s.err != err || !s.ended {
+			t.Errorf("Unexpected span: %+v", s)
+		}
+	})
+
+	t.Run("KeyError", func(t *testing.T) {
+		tracer := &recordingTracer{}
+		p := metrics.NewPrometheus()
+		m := &mockApiClient{}
+		handler := &NCANodeV3Handler{
+			Keys:    keys.Func(func(ctx context.Context) (entities.Key, error) { return entities.Key{}, errors.New("vault is sealed") }),
+			Timeout: time.Second,
+			Tracer:  tracer,
+			Metrics: p,
+			Api:     m,
+		}
+
+		_, err := handler.SignCmsBytes(context.Background(), []byte("data"), false)
+		if err == nil {
+			t.Fatal("Expected error, got nil")
+		}
+		if m.body != nil {
+			t.Errorf("Expected no request to be sent, got: %s", m.body)
+		}
+
+		if len(tracer.spans) != 1 {
+			t.Fatalf("Expected one span, got %d", len(tracer.spans))
+		}
+		s := tracer.spans[0]
+		if s.attrs[tracing.AttrErrorClass] != "key" || s.attrs[tracing.AttrEndpoint] != "/cms/sign" || s.err != err || !s.ended {
+			t.Errorf("Unexpected span: %+v", s)
+		}
+
+		if _, err := handler.Pkcs12Info(context.Background()); err == nil {
+			t.Fatal("Expected error, got nil")
+		}
+		if s := tracer.spans[1]; s.attrs[tracing.AttrEndpoint] != "/pkcs12/info" {
+			t.Errorf("Expected /pkcs12/info endpoint, got: %v", s.attrs)
+		}
+
+		var b strings.Builder
+		_ = p.Write(&b)
+		expected := `goncanode_calls_total{endpoint="/cms/sign",error_class="key",operation="SignCms",status="0",version="3.0"} 1`
+		if !strings.Contains(b.String(), expected) {
+			t.Errorf("Expected %s in:\n%s", expected, b.String())
+		}
+	})
+}
+
+func TestErrorClass(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected string
+	}{
+		{nil, ""},
+		{&TransportError{Op: "op", Err: api.ErrCircuitOpen}, "circuit_open"},
+		{&TransportError{Op: "op", Err: context.DeadlineExceeded}, "timeout"},
+		{&TransportError{Op: "op", Err: errors.New("connection reset")}, "transport"},
+		{&NodeError{Op: "op", Status: 500, Message: "Certificate revoked"}, "revoked_certificate"},
+		{&NodeError{Op: "op", Status: 500, Message: "Internal error"}, "node"},
+		{&DecodeError{Op: "op", What: "http response json", Err: errors.New("eof")}, "decode"},
+		{fmt.Errorf("op: wrapped: %w", errors.New("other")), "other"},
+	}
+
+	for _, tt := range tests {
+		if got := ErrorClass(tt.err); got != tt.expected {
+			t.Errorf("ErrorClass(%v): expected %q, got %q", tt.err, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+// Package tracing declares the hooks goncanode reports its spans to, adapters to OpenTelemetry or any other tracer
+// live in the application so goncanode doesn't depend on them.
+package tracing
+
+import (
+	"context"
+	"net/http"
+)
+
+type Tracer interface {
+	// Start opens a span as a child of the one in ctx and returns ctx carrying the new span.
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+	// Inject writes the trace context of the span in ctx into outgoing request headers, e.g. traceparent.
+	Inject(ctx context.Context, header http.Header)
+}
+
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	RecordError(err error)
+	End()
+}
+
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+func String(key string, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+func Int(key string, value int) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+func Float64(key string, value float64) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+const (
+	AttrVersion    = `ncanode.version`
+	AttrOperation  = `ncanode.operation`
+	AttrEndpoint   = `ncanode.endpoint`
+	AttrStatus     = `ncanode.status`
+	AttrErrorClass = `ncanode.error_class`
+	AttrLatencyMs  = `ncanode.latency_ms`
+
+	AttrHTTPMethod     = `http.request.method`
+	AttrHTTPUrl        = `url.full`
+	AttrHTTPStatusCode = `http.response.status_code`
+)
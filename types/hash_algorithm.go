@@ -1,5 +1,11 @@
 package types
 
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
 type HashAlgorithm string
 
 const (
@@ -15,3 +21,103 @@ const (
 	RIPEMD256   HashAlgorithm = "RIPEMD256"
 	GOST34311GT HashAlgorithm = "GOST34311GT"
 )
+
+var ErrUnknownHashAlgorithm = errors.New(`unknown hash algorithm`)
+
+type hashInfo struct {
+	oid    string
+	size   int
+	policy TsaPolicy
+}
+
+var hashAlgorithms = map[HashAlgorithm]hashInfo{
+	GOST34311:   {`1.2.398.3.10.1.3.1`, 32, TsaGostPolicy},
+	MD5:         {`1.2.840.113549.2.5`, 16, ``},
+	SHA1:        {`1.3.14.3.2.26`, 20, ``},
+	SHA224:      {`2.16.840.1.101.3.4.2.4`, 28, ``},
+	SHA256:      {`2.16.840.1.101.3.4.2.1`, 32, ``},
+	SHA384:      {`2.16.840.1.101.3.4.2.2`, 48, ``},
+	SHA512:      {`2.16.840.1.101.3.4.2.3`, 64, ``},
+	RIPEMD128:   {`1.3.36.3.2.2`, 16, ``},
+	RIPEMD160:   {`1.3.36.3.2.1`, 20, ``},
+	RIPEMD256:   {`1.3.36.3.2.3`, 32, ``},
+	GOST34311GT: {`1.2.398.3.10.1.3.2`, 64, TsaGostGtPolicy},
+}
+
+// versionHashAlgorithms lists the hash algorithms every NCANode version timestamps with,
+// v3 only knows the GOST TSA policies.
+var versionHashAlgorithms = map[Version][]HashAlgorithm{
+	NCAnodeV10: {GOST34311, MD5, SHA1, SHA224, SHA256, SHA384, SHA512, RIPEMD128, RIPEMD160, RIPEMD256, GOST34311GT},
+	NCAnodeV20: {GOST34311, MD5, SHA1, SHA224, SHA256, SHA384, SHA512, RIPEMD128, RIPEMD160, RIPEMD256, GOST34311GT},
+	NCAnodeV30: {GOST34311, GOST34311GT},
+}
+
+// ParseHashAlgorithm accepts the names of the constants above in any case.
+func ParseHashAlgorithm(s string) (HashAlgorithm, error) {
+	h := HashAlgorithm(strings.ToUpper(strings.TrimSpace(s)))
+	if !h.Valid() {
+		return ``, fmt.Errorf(`%w %q`, ErrUnknownHashAlgorithm, s)
+	}
+
+	return h, nil
+}
+
+// HashAlgorithms returns the hash algorithms the version supports, nil for an unknown version.
+func HashAlgorithms(v Version) []HashAlgorithm {
+	return append([]HashAlgorithm(nil), versionHashAlgorithms[v]...)
+}
+
+func (h HashAlgorithm) Valid() bool {
+	_, ok := hashAlgorithms[h]
+	return ok
+}
+
+// SupportedBy reports whether NCANode of the version timestamps with h.
+func (h HashAlgorithm) SupportedBy(v Version) bool {
+	for _, a := range versionHashAlgorithms[v] {
+		if a == h {
+			return true
+		}
+	}
+
+	return false
+}
+
+// OID returns the dotted object identifier of the algorithm, empty for unknown ones.
+func (h HashAlgorithm) OID() string {
+	return hashAlgorithms[h].oid
+}
+
+// Size returns the digest length in bytes, zero for unknown algorithms.
+func (h HashAlgorithm) Size() int {
+	return hashAlgorithms[h].size
+}
+
+// TsaPolicy returns the NCANode v3 TSA policy timestamping with h, empty when v3 has none.
+func (h HashAlgorithm) TsaPolicy() TsaPolicy {
+	return hashAlgorithms[h].policy
+}
+
+// MarshalText rejects unknown algorithms, the empty one stands for NCANode's default.
+func (h HashAlgorithm) MarshalText() ([]byte, error) {
+	if h != `` && !h.Valid() {
+		return nil, fmt.Errorf(`%w %q`, ErrUnknownHashAlgorithm, string(h))
+	}
+
+	return []byte(h), nil
+}
+
+func (h *HashAlgorithm) UnmarshalText(b []byte) error {
+	if len(b) == 0 {
+		*h = ``
+		return nil
+	}
+
+	p, err := ParseHashAlgorithm(string(b))
+	if err != nil {
+		return err
+	}
+	*h = p
+
+	return nil
+}
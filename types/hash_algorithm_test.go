@@ -0,0 +1,89 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParseHashAlgorithm(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected HashAlgorithm
+		err      bool
+	}{
+		{"SHA256", SHA256, false},
+		{" gost34311gt ", GOST34311GT, false},
+		{"SHA-256", "", true},
+		{"", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			h, err := ParseHashAlgorithm(tt.in)
+			if tt.err {
+				if !errors.Is(err, ErrUnknownHashAlgorithm) {
+					t.Errorf("Expected ErrUnknownHashAlgorithm, got: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no errors, got: %v", err)
+			}
+			if h != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, h)
+			}
+		})
+	}
+}
+
+func TestHashAlgorithm_Metadata(t *testing.T) {
+	for _, h := range HashAlgorithms(NCAnodeV10) {
+		if !h.Valid() || h.OID() == "" || h.Size() == 0 {
+			t.Errorf("Expected metadata for %s, got oid %q and size %d", h, h.OID(), h.Size())
+		}
+	}
+
+	if SHA256.OID() != "2.16.840.1.101.3.4.2.1" || SHA256.Size() != 32 {
+		t.Errorf("Unexpected SHA256 metadata: %s %d", SHA256.OID(), SHA256.Size())
+	}
+	if !GOST34311.SupportedBy(NCAnodeV30) || SHA256.SupportedBy(NCAnodeV30) || !SHA256.SupportedBy(NCAnodeV20) {
+		t.Errorf("Unexpected supported algorithms for v3: %v", HashAlgorithms(NCAnodeV30))
+	}
+	for _, h := range HashAlgorithms(NCAnodeV30) {
+		if h.TsaPolicy() == "" {
+			t.Errorf("Expected TSA policy for %s", h)
+		}
+	}
+	if GOST34311GT.TsaPolicy() != TsaGostGtPolicy || SHA256.TsaPolicy() != "" {
+		t.Errorf("Unexpected TSA policies: %s %s", GOST34311GT.TsaPolicy(), SHA256.TsaPolicy())
+	}
+	if HashAlgorithms("4.0") != nil {
+		t.Errorf("Expected no algorithms for unknown version")
+	}
+}
+
+func TestHashAlgorithm_JSON(t *testing.T) {
+	var c struct {
+		Hash HashAlgorithm `json:"hash"`
+	}
+
+	if err := json.Unmarshal([]byte(`{"hash":"sha512"}`), &c); err != nil || c.Hash != SHA512 {
+		t.Errorf("Expected SHA512, got %s: %v", c.Hash, err)
+	}
+	if err := json.Unmarshal([]byte(`{"hash":""}`), &c); err != nil || c.Hash != "" {
+		t.Errorf("Expected empty algorithm, got %s: %v", c.Hash, err)
+	}
+	if err := json.Unmarshal([]byte(`{"hash":"SHA-1"}`), &c); !errors.Is(err, ErrUnknownHashAlgorithm) {
+		t.Errorf("Expected ErrUnknownHashAlgorithm, got: %v", err)
+	}
+
+	c.Hash = GOST34311
+	if b, err := json.Marshal(c); err != nil || string(b) != `{"hash":"GOST34311"}` {
+		t.Errorf("Expected GOST34311, got %s: %v", b, err)
+	}
+	c.Hash = "SHA-1"
+	if _, err := json.Marshal(c); !errors.Is(err, ErrUnknownHashAlgorithm) {
+		t.Errorf("Expected ErrUnknownHashAlgorithm, got: %v", err)
+	}
+}
@@ -0,0 +1,8 @@
+package types
+
+type RevocationCheck string
+
+const (
+	OCSP RevocationCheck = "OCSP"
+	CRL  RevocationCheck = "CRL"
+)
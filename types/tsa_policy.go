@@ -0,0 +1,8 @@
+package types
+
+type TsaPolicy string
+
+const (
+	TsaGostPolicy   TsaPolicy = "TSA_GOST_POLICY"
+	TsaGostGtPolicy TsaPolicy = "TSA_GOSTGT_POLICY"
+)
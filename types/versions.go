@@ -4,5 +4,6 @@ type Version string
 
 const (
 	NCAnodeV10 Version = "1.0"
+	NCAnodeV20 Version = "2.0"
 	NCAnodeV30 Version = "3.0"
 )
@@ -0,0 +1,145 @@
+package goncanode
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/nbah1990/goncanode/entities"
+	"io"
+	"strconv"
+	"time"
+)
+
+var nodeTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.000-0700",
+	"2006-01-02T15:04:05.000Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"02.01.2006 15:04:05",
+}
+
+// rpcRequest is the JSON-RPC like envelope of NCANode 1.x and 2.x.
+type rpcRequest struct {
+	Version string      `json:"version"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+// nodeTime accepts every date representation NCANode versions are known to return:
+// ISO 8601 strings with or without zone, plain date-time strings and unix milliseconds.
+type nodeTime struct {
+	time.Time
+}
+
+func (t *nodeTime) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if len(b) == 0 || bytes.Equal(b, []byte(`null`)) || bytes.Equal(b, []byte(`""`)) {
+		return nil
+	}
+
+	if b[0] != '"' {
+		ms, err := strconv.ParseInt(string(b), 10, 64)
+		if err != nil {
+			return fmt.Errorf(`invalid time value %s`, b)
+		}
+		t.Time = time.UnixMilli(ms).UTC()
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	for _, l := range nodeTimeLayouts {
+		if v, err := time.Parse(l, s); err == nil {
+			t.Time = v
+			return nil
+		}
+	}
+
+	return fmt.Errorf(`invalid time value %q`, s)
+}
+
+func (t *nodeTime) ptr() *time.Time {
+	if t == nil || t.IsZero() {
+		return nil
+	}
+
+	v := t.Time
+	return &v
+}
+
+type nodeSubject struct {
+	DN           string `json:"dn"`
+	CommonName   string `json:"commonName"`
+	LastName     string `json:"lastName"`
+	SurName      string `json:"surName"`
+	Email        string `json:"email"`
+	Organization string `json:"organization"`
+	IIN          string `json:"iin"`
+	BIN          string `json:"bin"`
+	Country      string `json:"country"`
+	Locality     string `json:"locality"`
+	State        string `json:"state"`
+}
+
+func (s nodeSubject) entity() entities.DistinguishedName {
+	return entities.DistinguishedName(s)
+}
+
+func readCmsData(op string, data io.Reader) ([]byte, error) {
+	if data == nil {
+		return nil, &InputError{Op: op, Err: errNilReader}
+	}
+
+	b, err := io.ReadAll(data)
+	if err != nil {
+		return nil, &InputError{Op: op, Err: err}
+	}
+
+	return b, nil
+}
+
+func decodeCms(op string, cms string) (result entities.CmsResult, err error) {
+	der, err := base64.StdEncoding.DecodeString(cms)
+	if err != nil {
+		return result, &DecodeError{Op: op, What: `cms base64`, Err: err}
+	}
+
+	result.Der = der
+	result.Base64 = cms
+
+	return result, nil
+}
+
+func decodeCmsData(op string, data string) ([]byte, error) {
+	b, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, &DecodeError{Op: op, What: `data base64`, Err: err}
+	}
+
+	return b, nil
+}
+
+func cmsSigner(chain []entities.Certificate, signingTime *time.Time) entities.CmsSigner {
+	s := entities.CmsSigner{
+		ChainValid:  len(chain) > 0,
+		SigningTime: signingTime,
+	}
+
+	for i, c := range chain {
+		if i == 0 {
+			s.Certificate = c
+		} else {
+			s.Chain = append(s.Chain, c)
+		}
+		if !c.Valid {
+			s.ChainValid = false
+		}
+	}
+
+	return s
+}